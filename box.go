@@ -0,0 +1,200 @@
+package main
+
+// Evaluated box properties shared by every component that can be positioned,
+// sized, padded and bordered
+type boxData struct {
+	position   *Position
+	dimensions *Dimensions
+	padding    *Padding
+	border     *Border
+	style      *Style
+
+	// Size of the box the component is laid out in, 0 while unknown
+	parentWidth  int
+	parentHeight int
+
+	// Space the component can take, smaller than the parent when siblings
+	// already took part of it
+	limitWidth  int
+	limitHeight int
+}
+
+func (d *boxData) setParent(width int, height int) {
+	d.parentWidth = width
+	d.parentHeight = height
+	d.limitWidth = width
+	d.limitHeight = height
+}
+
+// Takes the parent size and the space left from the constraints of a measure
+func (d *boxData) constrain(constraints Constraints) {
+	d.setParent(constraints.parentSize())
+	d.limitWidth = constraints.MaxWidth
+	d.limitHeight = constraints.MaxHeight
+}
+
+// Position of the box once its final size is known
+func (d *boxData) getPosition(width int, height int) (int, int) {
+	if d.position != nil {
+		return d.position.Resolve(d.parentWidth, d.parentHeight, width, height)
+	}
+	return 1, 1
+}
+
+// Size of the box, an axis that evaluates to 0 is sized after the content
+func (d *boxData) getDimensions() (int, int) {
+	if d.dimensions != nil {
+		offsetX, offsetY := 0, 0
+
+		if d.position != nil {
+			offsetX, offsetY = d.position.offset(d.parentWidth, d.parentHeight)
+		}
+
+		return d.dimensions.Resolve(d.parentWidth, d.parentHeight, offsetX, offsetY)
+	}
+	return 1, 1
+}
+
+func (d *boxData) hasDimensions() bool {
+	return d.dimensions != nil
+}
+
+func (d *boxData) getPadding() (int, int, int, int) {
+	if d.padding != nil {
+		return d.padding.Eval()
+	}
+	return 0, 0, 0, 0
+}
+
+func (d *boxData) getBorders() (bool, bool, bool, bool) {
+	if d.border != nil {
+		return d.border.Eval()
+	}
+	return false, false, false, false
+}
+
+func (d *boxData) getBorderSizes() (int, int, int, int) {
+	if d.border != nil {
+		return d.border.EvalSizes()
+	}
+	return 0, 0, 0, 0
+}
+
+func (d *boxData) getBorderChars() (rune, rune, rune, rune, rune, rune, rune, rune) {
+	if d.border != nil {
+		return d.border.EvalBorderRunes()
+	}
+	return rune(' '), rune(' '), rune(' '), rune(' '), rune(' '), rune(' '), rune(' '), rune(' ')
+}
+
+func (d *boxData) hasBorder() bool {
+	bt, br, bb, bl := d.getBorderSizes()
+	return bt+br+bb+bl > 0
+}
+
+func (d *boxData) getStyle() Style {
+	if d.style != nil {
+		return d.style.Eval()
+	}
+	return Style{}
+}
+
+// Space taken by padding and border on each side
+func (d *boxData) getInsets() (int, int, int, int) {
+	borderSize := BoolToInt(d.hasBorder())
+	pt, pr, pb, pl := d.getPadding()
+
+	return pt + borderSize, pr + borderSize, pb + borderSize, pl + borderSize
+}
+
+// Constraints for the children of the box: its own fixed size, or what the
+// parent offers along auto axes, without padding and border
+func (d *boxData) getContentConstraints() Constraints {
+	width, height := d.limitWidth, d.limitHeight
+
+	if d.hasDimensions() {
+		fixedW, fixedH := d.getDimensions()
+
+		if fixedW > 0 {
+			width = fixedW
+		}
+
+		if fixedH > 0 {
+			height = fixedH
+		}
+	}
+
+	t, r, b, l := d.getInsets()
+	constraints := Constraints{}
+
+	if width > 0 {
+		constraints.MaxWidth = max(width-l-r, 1)
+	}
+
+	if height > 0 {
+		constraints.MaxHeight = max(height-t-b, 1)
+	}
+
+	return constraints
+}
+
+// Size of the whole box around content of the given size, axes with a fixed
+// size keep it
+func (d *boxData) measureFrame(contentW int, contentH int) (int, int) {
+	t, r, b, l := d.getInsets()
+	width := max(contentW, 1) + l + r
+	height := max(contentH, 1) + t + b
+
+	if d.hasDimensions() {
+		fixedW, fixedH := d.getDimensions()
+
+		if fixedW > 0 {
+			width = fixedW
+		}
+
+		if fixedH > 0 {
+			height = fixedH
+		}
+	}
+
+	return width, height
+}
+
+func (d *boxData) getContentRect(rect Rect) Rect {
+	t, r, b, l := d.getInsets()
+	return rect.Inset(t, r, b, l)
+}
+
+// Fills the area of the box with its style and draws the border around it
+func (d *boxData) drawFrame(canvas *Matrix, rect Rect) {
+	if rect.IsEmpty() {
+		return
+	}
+
+	style := d.getStyle()
+	frame := NewMatrix(rect.Width, rect.Height)
+	frame.Fill(NewCell(rune(' '), style))
+
+	if d.hasBorder() {
+		btl, bt, btr, br, bbl, bb, bbr, bl := d.getBorderChars()
+		frame.Border(1, style, bt, bl, bb, br, btl, btr, bbl, bbr)
+	}
+
+	canvas.Blit(rect, frame)
+}
+
+func newBoxData(
+	position *Position,
+	dimensions *Dimensions,
+	padding *Padding,
+	border *Border,
+	style *Style,
+) *boxData {
+	return &boxData{
+		position:   position,
+		dimensions: dimensions,
+		padding:    padding,
+		border:     border,
+		style:      style,
+	}
+}
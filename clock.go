@@ -0,0 +1,123 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Source of time for the timers of the context. The real clock is used by
+// default, a FakeClock can be set on the renderer so timers only fire when the
+// clock is advanced by hand.
+type Clock interface {
+	Now() time.Time
+	AfterFunc(d time.Duration, f func()) ClockTimer
+}
+
+type ClockTimer interface {
+	Stop() bool
+}
+
+type realClock struct {
+}
+
+func (c *realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (c *realClock) AfterFunc(d time.Duration, f func()) ClockTimer {
+	// Callbacks run in a goroutine of their own
+	return time.AfterFunc(d, func() {
+		defer recoverPanic()
+		f()
+	})
+}
+
+type FakeClock struct {
+	now    time.Time
+	timers []*fakeTimer
+	mutex  sync.Mutex
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.now
+}
+
+func (c *FakeClock) AfterFunc(d time.Duration, f func()) ClockTimer {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	timer := &fakeTimer{clock: c, deadline: c.now.Add(max(d, 0)), callback: f}
+	c.timers = append(c.timers, timer)
+
+	return timer
+}
+
+// Moves the clock forward, firing the timers that become due in the order of
+// their deadlines. Timers scheduled by the callbacks fire too if they fall
+// within the same advance.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	target := c.now.Add(d)
+	c.mutex.Unlock()
+
+	for {
+		c.mutex.Lock()
+		timer := c.nextDue(target)
+
+		if timer == nil {
+			c.now = target
+			c.mutex.Unlock()
+			return
+		}
+
+		c.now = timer.deadline
+		c.remove(timer)
+		c.mutex.Unlock()
+
+		timer.callback()
+	}
+}
+
+func (c *FakeClock) nextDue(target time.Time) *fakeTimer {
+	sort.SliceStable(c.timers, func(i, j int) bool {
+		return c.timers[i].deadline.Before(c.timers[j].deadline)
+	})
+
+	if len(c.timers) == 0 || c.timers[0].deadline.After(target) {
+		return nil
+	}
+
+	return c.timers[0]
+}
+
+func (c *FakeClock) remove(timer *fakeTimer) bool {
+	for i, t := range c.timers {
+		if t == timer {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+type fakeTimer struct {
+	clock    *FakeClock
+	deadline time.Time
+	callback func()
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+
+	return t.clock.remove(t)
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type colorKind int
+
+const (
+	colorDefault colorKind = iota
+	colorAnsi
+	colorIndexed
+	colorRGB
+)
+
+type Color struct {
+	kind  colorKind
+	value uint32
+}
+
+var (
+	ColorDefault = Color{kind: colorDefault}
+
+	ColorBlack         = Color{kind: colorAnsi, value: 0}
+	ColorRed           = Color{kind: colorAnsi, value: 1}
+	ColorGreen         = Color{kind: colorAnsi, value: 2}
+	ColorYellow        = Color{kind: colorAnsi, value: 3}
+	ColorBlue          = Color{kind: colorAnsi, value: 4}
+	ColorMagenta       = Color{kind: colorAnsi, value: 5}
+	ColorCyan          = Color{kind: colorAnsi, value: 6}
+	ColorWhite         = Color{kind: colorAnsi, value: 7}
+	ColorBrightBlack   = Color{kind: colorAnsi, value: 8}
+	ColorBrightRed     = Color{kind: colorAnsi, value: 9}
+	ColorBrightGreen   = Color{kind: colorAnsi, value: 10}
+	ColorBrightYellow  = Color{kind: colorAnsi, value: 11}
+	ColorBrightBlue    = Color{kind: colorAnsi, value: 12}
+	ColorBrightMagenta = Color{kind: colorAnsi, value: 13}
+	ColorBrightCyan    = Color{kind: colorAnsi, value: 14}
+	ColorBrightWhite   = Color{kind: colorAnsi, value: 15}
+)
+
+func (c Color) IsDefault() bool {
+	return c.kind == colorDefault
+}
+
+// Returns the SGR parameters selecting this color, either as foreground or
+// as background
+func (c Color) sgr(background bool) string {
+	switch c.kind {
+	case colorAnsi:
+		base := 30
+		if c.value >= 8 {
+			base = 90
+		}
+
+		if background {
+			base += 10
+		}
+
+		return strconv.Itoa(base + int(c.value%8))
+	case colorIndexed:
+		if background {
+			return "48;5;" + strconv.Itoa(int(c.value))
+		}
+		return "38;5;" + strconv.Itoa(int(c.value))
+	case colorRGB:
+		r, g, b := c.value>>16&0xff, c.value>>8&0xff, c.value&0xff
+		channels := strconv.Itoa(int(r)) + ";" + strconv.Itoa(int(g)) + ";" + strconv.Itoa(int(b))
+
+		if background {
+			return "48;2;" + channels
+		}
+		return "38;2;" + channels
+	}
+
+	if background {
+		return "49"
+	}
+	return "39"
+}
+
+// Returns the closest color the terminal is able to display
+func (c Color) downsample(support TerminalColor) Color {
+	if support == NoColor {
+		return ColorDefault
+	}
+
+	if c.kind == colorDefault || c.kind == colorAnsi || support == TrueColor {
+		return c
+	}
+
+	if c.kind == colorIndexed && support == Color256 {
+		return c
+	}
+
+	key := colorCacheKey{color: c, support: support}
+
+	colorCache.mutex.Lock()
+	defer colorCache.mutex.Unlock()
+
+	if cached, ok := colorCache.values[key]; ok {
+		return cached
+	}
+
+	r, g, b := c.rgb()
+	var result Color
+
+	if support == Color256 {
+		// The first 16 entries follow the terminal theme, so only the fixed
+		// color cube and grayscale ramp are reliable matches
+		result = ColorIndex(uint8(nearestPaletteIndex(r, g, b, 16, 256)))
+	} else {
+		result = Color{kind: colorAnsi, value: uint32(nearestPaletteIndex(r, g, b, 0, 16))}
+	}
+
+	colorCache.values[key] = result
+	return result
+}
+
+func (c Color) rgb() (int, int, int) {
+	switch c.kind {
+	case colorRGB:
+		return int(c.value >> 16 & 0xff), int(c.value >> 8 & 0xff), int(c.value & 0xff)
+	case colorAnsi, colorIndexed:
+		return paletteRGB(int(c.value))
+	}
+
+	return 0, 0, 0
+}
+
+type colorCacheKey struct {
+	color   Color
+	support TerminalColor
+}
+
+var colorCache = struct {
+	mutex  sync.Mutex
+	values map[colorCacheKey]Color
+}{values: map[colorCacheKey]Color{}}
+
+// Standard xterm values for the 16 ANSI colors
+var ansiPalette = [16][3]int{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+var cubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+
+func paletteRGB(index int) (int, int, int) {
+	if index < 16 {
+		c := ansiPalette[index]
+		return c[0], c[1], c[2]
+	}
+
+	if index < 232 {
+		index -= 16
+		return cubeLevels[index/36], cubeLevels[index/6%6], cubeLevels[index%6]
+	}
+
+	gray := 8 + (index-232)*10
+	return gray, gray, gray
+}
+
+func nearestPaletteIndex(r int, g int, b int, from int, to int) int {
+	best := from
+	bestDistance := -1
+
+	for i := from; i < to; i++ {
+		pr, pg, pb := paletteRGB(i)
+		distance := colorDistance(r, g, b, pr, pg, pb)
+
+		if bestDistance < 0 || distance < bestDistance {
+			best = i
+			bestDistance = distance
+		}
+	}
+
+	return best
+}
+
+// Weighted euclidean distance ("redmean"), which approximates how different
+// two colors look to the human eye much better than plain RGB distance
+func colorDistance(r1 int, g1 int, b1 int, r2 int, g2 int, b2 int) int {
+	mean := (r1 + r2) / 2
+	dr := r1 - r2
+	dg := g1 - g2
+	db := b1 - b2
+
+	return ((512+mean)*dr*dr)>>8 + 4*dg*dg + ((767-mean)*db*db)>>8
+}
+
+func ColorIndex(index uint8) Color {
+	return Color{kind: colorIndexed, value: uint32(index)}
+}
+
+func ColorRGB(r uint8, g uint8, b uint8) Color {
+	return Color{kind: colorRGB, value: uint32(r)<<16 | uint32(g)<<8 | uint32(b)}
+}
+
+// Parses colors in the #rrggbb or #rgb notations
+func ColorHex(hex string) Color {
+	value := strings.TrimPrefix(hex, "#")
+
+	if len(value) == 3 {
+		value = string([]byte{value[0], value[0], value[1], value[1], value[2], value[2]})
+	}
+
+	if len(value) != 6 {
+		fatalf("Invalid hex color: '%s'", hex)
+	}
+
+	parsed, err := strconv.ParseUint(value, 16, 32)
+
+	if err != nil {
+		fatalf("Invalid hex color: '%s'", hex)
+	}
+
+	return Color{kind: colorRGB, value: uint32(parsed)}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// Work returned by a screen from OnEvent or Update. Commands run in their own
+// goroutine so they can block, and the event they return is delivered back to
+// the screen that issued them through the main loop. A nil event delivers
+// nothing. The context is cancelled once the screen is destroyed, and results
+// that arrive after that are dropped.
+type Cmd func(ctx context.Context) Event
+
+// Runs the commands concurrently, their results arrive in any order
+func Batch(cmds ...Cmd) Cmd {
+	cmds = compactCmds(cmds)
+
+	if len(cmds) == 0 {
+		return nil
+	}
+
+	return func(ctx context.Context) Event {
+		return batchEvent(cmds)
+	}
+}
+
+// Runs the commands one after the other, each one starting once the result of
+// the previous one has been delivered
+func Sequence(cmds ...Cmd) Cmd {
+	cmds = compactCmds(cmds)
+
+	if len(cmds) == 0 {
+		return nil
+	}
+
+	return func(ctx context.Context) Event {
+		return sequenceEvent(cmds)
+	}
+}
+
+func compactCmds(cmds []Cmd) []Cmd {
+	result := []Cmd{}
+
+	for _, cmd := range cmds {
+		if cmd != nil {
+			result = append(result, cmd)
+		}
+	}
+
+	return result
+}
+
+type batchEvent []Cmd
+
+func (e batchEvent) Payload() map[string]any {
+	return map[string]any{
+		"commands": len(e),
+	}
+}
+
+type sequenceEvent []Cmd
+
+func (e sequenceEvent) Payload() map[string]any {
+	return map[string]any{
+		"commands": len(e),
+	}
+}
+
+// Result of a command on its way to the main loop
+type commandResult struct {
+	entry *screenEntry
+	event Event
+	done  chan struct{}
+}
+
+func (r *Renderer) runCommand(entry *screenEntry, cmd Cmd) {
+	if cmd == nil {
+		return
+	}
+
+	go func() {
+		defer r.terminal.RecoverPanic()
+		r.execute(entry, cmd)
+	}()
+}
+
+// Runs a command in the calling goroutine and waits until its result is
+// delivered, so sequences keep their order
+func (r *Renderer) execute(entry *screenEntry, cmd Cmd) {
+	event := cmd(entry.ctx)
+
+	if event == nil || entry.ctx.Err() != nil {
+		return
+	}
+
+	switch event := event.(type) {
+	case batchEvent:
+		// Waits for every command, so a batch in a sequence finishes before
+		// the next step starts
+		var wg sync.WaitGroup
+
+		for _, cmd := range event {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+				defer r.terminal.RecoverPanic()
+				r.execute(entry, cmd)
+			}()
+		}
+
+		wg.Wait()
+	case sequenceEvent:
+		for _, cmd := range event {
+			r.execute(entry, cmd)
+		}
+	default:
+		result := commandResult{entry: entry, event: event, done: make(chan struct{})}
+
+		select {
+		case r.commands <- result:
+			select {
+			case <-result.done:
+			case <-entry.ctx.Done():
+			}
+		case <-entry.ctx.Done():
+		}
+	}
+}
+
+func (r *Renderer) handleCommandResult(result commandResult) {
+	defer close(result.done)
+
+	// The screen was destroyed while the result was waiting
+	if result.entry.ctx.Err() != nil {
+		return
+	}
+
+	r.sendEvent(result.entry, result.event)
+
+	if result.entry == r.screens.Peek() {
+		r.context.RequestRefresh()
+	}
+}
@@ -1,5 +1,106 @@
 package main
 
+// Components are laid out in two passes. Measure asks for the size the
+// component wants within the space offered by its parent, then Arrange draws
+// it into the area the parent decided to give it. Render draws a component on
+// its own, at the size it wants when nothing constrains it.
 type Component interface {
 	Render() (*Matrix, int, int)
+	Measure(constraints Constraints) (int, int)
+	Arrange(canvas *Matrix, rect Rect)
+}
+
+// Components placed freely inside their parent, resolving their position once
+// the size of the parent and their own size are known
+type positioned interface {
+	locate(parentW int, parentH int, width int, height int) (int, int)
+}
+
+// Space offered to a component when it is measured, a max of 0 means the axis
+// is unbounded. The parent size is what relative lengths resolve against, it
+// differs from the max when siblings already took part of the parent, and 0
+// means it is the same as the max.
+type Constraints struct {
+	MaxWidth     int
+	MaxHeight    int
+	ParentWidth  int
+	ParentHeight int
+}
+
+func (c Constraints) parentSize() (int, int) {
+	width, height := c.ParentWidth, c.ParentHeight
+
+	if width <= 0 {
+		width = c.MaxWidth
+	}
+
+	if height <= 0 {
+		height = c.MaxHeight
+	}
+
+	return width, height
+}
+
+func (c Constraints) Clamp(width int, height int) (int, int) {
+	if c.MaxWidth > 0 && width > c.MaxWidth {
+		width = c.MaxWidth
+	}
+
+	if c.MaxHeight > 0 && height > c.MaxHeight {
+		height = c.MaxHeight
+	}
+
+	return width, height
+}
+
+type Rect struct {
+	X      int
+	Y      int
+	Width  int
+	Height int
+}
+
+func (r Rect) IsEmpty() bool {
+	return r.Width < 1 || r.Height < 1
+}
+
+func (r Rect) Contains(x int, y int) bool {
+	return x >= r.X && x < r.X+r.Width && y >= r.Y && y < r.Y+r.Height
+}
+
+func (r Rect) Inset(t int, right int, b int, l int) Rect {
+	return Rect{
+		X:      r.X + l,
+		Y:      r.Y + t,
+		Width:  defaultToZero(r.Width - l - right),
+		Height: defaultToZero(r.Height - t - b),
+	}
+}
+
+func (r Rect) Intersect(other Rect) Rect {
+	x := max(r.X, other.X)
+	y := max(r.Y, other.Y)
+	right := min(r.X+r.Width, other.X+other.Width)
+	bottom := min(r.Y+r.Height, other.Y+other.Height)
+
+	return Rect{X: x, Y: y, Width: defaultToZero(right - x), Height: defaultToZero(bottom - y)}
+}
+
+// Draws a component into a matrix of the size it wants
+func renderComponent(component Component) (*Matrix, int, int) {
+	width, height := component.Measure(Constraints{})
+	width, height = defaultToOne(width), defaultToOne(height)
+
+	matrix := NewMatrix(width, height)
+	component.Arrange(matrix, Rect{X: 1, Y: 1, Width: width, Height: height})
+	x, y := locate(component, 0, 0, width, height)
+
+	return matrix, x, y
+}
+
+func locate(component Component, parentW int, parentH int, width int, height int) (int, int) {
+	if p, ok := component.(positioned); ok {
+		return p.locate(parentW, parentH, width, height)
+	}
+	return 1, 1
 }
@@ -1,5 +1,9 @@
 package main
 
+import (
+	"math"
+)
+
 type BorderType int
 type BorderStyle int
 
@@ -18,29 +22,200 @@ const (
 	BorderRounded
 )
 
+type Unit int
+type Anchor int
+
+const (
+	UnitCells Unit = iota
+	UnitPercent
+	UnitFraction
+	UnitAuto
+	UnitFill
+)
+
+const (
+	AnchorTopLeft Anchor = iota
+	AnchorTop
+	AnchorTopRight
+	AnchorLeft
+	AnchorCenter
+	AnchorRight
+	AnchorBottomLeft
+	AnchorBottom
+	AnchorBottomRight
+)
+
+// A size or coordinate, either absolute in cells or relative to the parent
+// box, which is only known at layout time
+type Length struct {
+	unit  Unit
+	value float64
+}
+
+func (l Length) Unit() Unit {
+	return l.unit
+}
+
+func (l Length) IsRelative() bool {
+	return l.unit != UnitCells
+}
+
+// Resolves the length against the size of the parent. Auto lengths, and
+// relative ones while the parent is still unknown, resolve to 0, which means
+// the size is taken from the content. Fill takes what is left of the parent
+// after the offset.
+func (l Length) resolve(parent int, offset int) int {
+	switch l.unit {
+	case UnitCells:
+		return int(l.value)
+	case UnitAuto:
+		return 0
+	}
+
+	if parent <= 0 {
+		return 0
+	}
+
+	switch l.unit {
+	case UnitPercent:
+		return max(int(math.Round(float64(parent)*l.value/100)), 1)
+	case UnitFraction:
+		return max(int(math.Round(float64(parent)*l.value)), 1)
+	case UnitFill:
+		return max(parent-offset, 1)
+	}
+
+	return 0
+}
+
+func Cells(n int) Length {
+	return Length{unit: UnitCells, value: float64(n)}
+}
+
+func Percent(p float64) Length {
+	return Length{unit: UnitPercent, value: p}
+}
+
+func Fraction(numerator int, denominator int) Length {
+	if denominator == 0 {
+		fatal("Fraction denominator cannot be 0.")
+	}
+
+	return Length{unit: UnitFraction, value: float64(numerator) / float64(denominator)}
+}
+
+func Auto() Length {
+	return Length{unit: UnitAuto}
+}
+
+func Fill() Length {
+	return Length{unit: UnitFill}
+}
+
 type Position struct {
-	x int
-	y int
+	x      Length
+	y      Length
+	anchor Anchor
 }
 
 func (p *Position) Eval() (int, int) {
-	return defaultToOne(p.x), defaultToOne(p.y)
+	return p.Resolve(0, 0, 0, 0)
+}
+
+// Resolves the position of a box of the given size inside its parent. Top-left
+// positions are coordinates starting at 1, any other anchor treats x and y as
+// offsets from the anchored spot.
+func (p *Position) Resolve(parentW int, parentH int, width int, height int) (int, int) {
+	if p.anchor == AnchorTopLeft {
+		return resolveCoordinate(p.x, parentW), resolveCoordinate(p.y, parentH)
+	}
+
+	col := int(p.anchor) % 3
+	row := int(p.anchor) / 3
+
+	x := anchorBase(col, parentW, width) + 1 + p.x.resolve(parentW, 0)
+	y := anchorBase(row, parentH, height) + 1 + p.y.resolve(parentH, 0)
+
+	return defaultToOne(x), defaultToOne(y)
+}
+
+// How far into the parent the box starts, only known without the size of the
+// box for top-left positions
+func (p *Position) offset(parentW int, parentH int) (int, int) {
+	if p.anchor != AnchorTopLeft {
+		return 0, 0
+	}
+
+	x, y := p.Resolve(parentW, parentH, 0, 0)
+	return x - 1, y - 1
+}
+
+// Offset of a box along one axis for the start, middle and end of the parent
+func anchorBase(slot int, parent int, size int) int {
+	switch slot {
+	case 1:
+		return (parent - size) / 2
+	case 2:
+		return parent - size
+	}
+	return 0
+}
+
+func resolveCoordinate(l Length, parent int) int {
+	if l.IsRelative() {
+		return defaultToOne(l.resolve(parent, 0) + 1)
+	}
+	return defaultToOne(l.resolve(parent, 0))
 }
 
 func NewXY(x int, y int) *Position {
+	return &Position{x: Cells(x), y: Cells(y)}
+}
+
+func NewPosition(x Length, y Length) *Position {
 	return &Position{x: x, y: y}
 }
 
+func NewAnchor(anchor Anchor) *Position {
+	return &Position{x: Cells(0), y: Cells(0), anchor: anchor}
+}
+
+func NewAnchorOffset(anchor Anchor, x int, y int) *Position {
+	return &Position{x: Cells(x), y: Cells(y), anchor: anchor}
+}
+
 type Dimensions struct {
-	width  int
-	height int
+	width  Length
+	height Length
 }
 
 func (d *Dimensions) Eval() (int, int) {
-	return defaultToOne(d.width), defaultToOne(d.height)
+	return defaultToOne(d.width.resolve(0, 0)), defaultToOne(d.height.resolve(0, 0))
+}
+
+// Resolves the dimensions against the parent box, offsetX and offsetY are how
+// far into the parent the box starts. An axis that resolves to 0 is sized
+// after the content.
+func (d *Dimensions) Resolve(parentW int, parentH int, offsetX int, offsetY int) (int, int) {
+	width := d.width.resolve(parentW, offsetX)
+	height := d.height.resolve(parentH, offsetY)
+
+	if !d.width.IsRelative() {
+		width = defaultToOne(width)
+	}
+
+	if !d.height.IsRelative() {
+		height = defaultToOne(height)
+	}
+
+	return width, height
 }
 
 func NewWH(width int, height int) *Dimensions {
+	return &Dimensions{width: Cells(width), height: Cells(height)}
+}
+
+func NewDimensions(width Length, height Length) *Dimensions {
 	return &Dimensions{width: width, height: height}
 }
 
@@ -263,16 +438,103 @@ func NewBorder(s *BorderSide) *Border {
 	return &Border{t: t, r: r, b: b, l: l}
 }
 
+type ControlChars int
+
+const (
+	// Control characters are removed from the text
+	ControlStrip ControlChars = iota
+	// Control characters are shown with their Unicode control pictures, like
+	// ␛ for escape
+	ControlPictures
+)
+
+type TextAlign int
+
+const (
+	TextAlignLeft TextAlign = iota
+	TextAlignCenter
+	TextAlignRight
+	// Spreads the words so the line takes the whole width, the last line of
+	// each paragraph is left aligned
+	TextAlignJustify
+)
+
+type VerticalAlign int
+
+const (
+	VerticalTop VerticalAlign = iota
+	VerticalMiddle
+	VerticalBottom
+)
+
+// What is shown when the text has more lines than it is allowed to
+type Overflow int
+
+const (
+	// Follows the ellipsis of the props, an ellipsis at the end or a clip
+	OverflowDefault Overflow = iota
+	// The lines that do not fit are cut without any marker
+	OverflowClip
+	// The marker ends the last line, hiding the end of the text
+	OverflowEnd
+	// The marker starts the first line, hiding the start of the text
+	OverflowStart
+	// The marker sits in the middle line, keeping the start and the end of
+	// the text, which suits file paths
+	OverflowMiddle
+	// The last columns of the last line are dimmed
+	OverflowFade
+)
+
+// Marker used when the props do not set one
+const defaultOverflowMarker = "..."
+
+// Columns between tab stops when none is set
+const defaultTabSize = 8
+
 type TextProps struct {
 	maxLines int
 	ellipsis bool
 	wordWrap bool
+	tabSize  int
+	control  ControlChars
+	align    TextAlign
+	vertical VerticalAlign
+	overflow Overflow
+	marker   string
 }
 
 func (p *TextProps) Eval() (int, bool, bool) {
 	return defaultToZero(p.maxLines), p.ellipsis, p.wordWrap
 }
 
+// Sets the number of columns between tab stops, 0 uses the default of 8
+func (p *TextProps) WithTabSize(size int) *TextProps {
+	p.tabSize = defaultToZero(size)
+	return p
+}
+
+// Aligns every line inside the width of the box, and the lines as a whole
+// inside its height when the height is fixed
+func (p *TextProps) WithAlign(align TextAlign, vertical VerticalAlign) *TextProps {
+	p.align = align
+	p.vertical = vertical
+	return p
+}
+
+// Sets how hidden text is shown. The marker replaces the default "...", and a
+// %d in it is replaced with the number of hidden lines, as in "+%d more".
+func (p *TextProps) WithOverflow(overflow Overflow, marker string) *TextProps {
+	p.overflow = overflow
+	p.marker = marker
+	return p
+}
+
+func (p *TextProps) WithControlChars(control ControlChars) *TextProps {
+	p.control = control
+	return p
+}
+
 func NewTextProps(maxLines int, ellipsis bool, wordWrap bool) *TextProps {
 	return &TextProps{maxLines: maxLines, ellipsis: ellipsis, wordWrap: wordWrap}
 }
@@ -0,0 +1,288 @@
+package main
+
+// Lays its children out from left to right, aligned to the top
+type Row struct {
+	Children   []Component
+	Gap        int
+	Position   *Position
+	Dimensions *Dimensions
+	Padding    *Padding
+	Border     *Border
+	Style      *Style
+
+	// Content constraints of the last measure, the children are arranged
+	// against the same size they were measured against
+	measured Constraints
+}
+
+func (r *Row) Render() (*Matrix, int, int) {
+	return renderComponent(r)
+}
+
+func (r *Row) Measure(constraints Constraints) (int, int) {
+	data := r.boxData(constraints.parentSize())
+	data.constrain(constraints)
+	content := data.getContentConstraints()
+	r.measured = content
+	gap := defaultToZero(r.Gap)
+	width, height := 0, 0
+	placed := 0
+
+	for _, child := range r.Children {
+		if child == nil {
+			continue
+		}
+
+		if placed > 0 {
+			width += gap
+		}
+		placed++
+
+		childW, childH := child.Measure(remainingConstraints(content, width, 0))
+		width += childW
+		height = max(height, childH)
+	}
+
+	return constraints.Clamp(data.measureFrame(width, height))
+}
+
+func (r *Row) Arrange(canvas *Matrix, rect Rect) {
+	data := r.boxData(rect.Width, rect.Height)
+	data.drawFrame(canvas, rect)
+
+	content := data.getContentRect(rect)
+	parentW, parentH := arrangeBase(r.measured, content)
+	gap := defaultToZero(r.Gap)
+	offset := 0
+
+	for _, child := range r.Children {
+		if child == nil {
+			continue
+		}
+
+		constraints := Constraints{
+			MaxWidth:     max(content.Width-offset, 1),
+			MaxHeight:    content.Height,
+			ParentWidth:  parentW,
+			ParentHeight: parentH,
+		}
+		childW, childH := child.Measure(constraints)
+		childRect := Rect{X: content.X + offset, Y: content.Y, Width: childW, Height: childH}
+
+		arrangeChild(canvas, child, childRect.Intersect(content))
+		offset += childW + gap
+	}
+}
+
+func (r *Row) locate(parentW int, parentH int, width int, height int) (int, int) {
+	return r.boxData(parentW, parentH).getPosition(width, height)
+}
+
+func (r *Row) boxData(parentW int, parentH int) *boxData {
+	data := newBoxData(r.Position, r.Dimensions, r.Padding, r.Border, r.Style)
+	data.setParent(parentW, parentH)
+	return data
+}
+
+// Lays its children out from top to bottom, aligned to the left
+type Column struct {
+	Children   []Component
+	Gap        int
+	Position   *Position
+	Dimensions *Dimensions
+	Padding    *Padding
+	Border     *Border
+	Style      *Style
+
+	// Content constraints of the last measure, the children are arranged
+	// against the same size they were measured against
+	measured Constraints
+}
+
+func (c *Column) Render() (*Matrix, int, int) {
+	return renderComponent(c)
+}
+
+func (c *Column) Measure(constraints Constraints) (int, int) {
+	data := c.boxData(constraints.parentSize())
+	data.constrain(constraints)
+	content := data.getContentConstraints()
+	c.measured = content
+	gap := defaultToZero(c.Gap)
+	width, height := 0, 0
+	placed := 0
+
+	for _, child := range c.Children {
+		if child == nil {
+			continue
+		}
+
+		if placed > 0 {
+			height += gap
+		}
+		placed++
+
+		childW, childH := child.Measure(remainingConstraints(content, 0, height))
+		width = max(width, childW)
+		height += childH
+	}
+
+	return constraints.Clamp(data.measureFrame(width, height))
+}
+
+func (c *Column) Arrange(canvas *Matrix, rect Rect) {
+	data := c.boxData(rect.Width, rect.Height)
+	data.drawFrame(canvas, rect)
+
+	content := data.getContentRect(rect)
+	parentW, parentH := arrangeBase(c.measured, content)
+	gap := defaultToZero(c.Gap)
+	offset := 0
+
+	for _, child := range c.Children {
+		if child == nil {
+			continue
+		}
+
+		constraints := Constraints{
+			MaxWidth:     content.Width,
+			MaxHeight:    max(content.Height-offset, 1),
+			ParentWidth:  parentW,
+			ParentHeight: parentH,
+		}
+		childW, childH := child.Measure(constraints)
+		childRect := Rect{X: content.X, Y: content.Y + offset, Width: childW, Height: childH}
+
+		arrangeChild(canvas, child, childRect.Intersect(content))
+		offset += childH + gap
+	}
+}
+
+func (c *Column) locate(parentW int, parentH int, width int, height int) (int, int) {
+	return c.boxData(parentW, parentH).getPosition(width, height)
+}
+
+func (c *Column) boxData(parentW int, parentH int) *boxData {
+	data := newBoxData(c.Position, c.Dimensions, c.Padding, c.Border, c.Style)
+	data.setParent(parentW, parentH)
+	return data
+}
+
+// Draws its children on top of each other, in order, each one at its own
+// position relative to the content area. Later children cover earlier ones.
+type ZStack struct {
+	Children   []Component
+	Position   *Position
+	Dimensions *Dimensions
+	Padding    *Padding
+	Border     *Border
+	Style      *Style
+
+	// Content constraints of the last measure, the children are arranged
+	// against the same size they were measured against
+	measured Constraints
+}
+
+func (s *ZStack) Render() (*Matrix, int, int) {
+	return renderComponent(s)
+}
+
+func (s *ZStack) Measure(constraints Constraints) (int, int) {
+	data := s.boxData(constraints.parentSize())
+	data.constrain(constraints)
+	content := data.getContentConstraints()
+	s.measured = content
+	width, height := 0, 0
+
+	for _, child := range s.Children {
+		if child == nil {
+			continue
+		}
+
+		childW, childH := child.Measure(content)
+		x, y := locate(child, content.MaxWidth, content.MaxHeight, childW, childH)
+
+		width = max(width, x-1+childW)
+		height = max(height, y-1+childH)
+	}
+
+	return constraints.Clamp(data.measureFrame(width, height))
+}
+
+func (s *ZStack) Arrange(canvas *Matrix, rect Rect) {
+	data := s.boxData(rect.Width, rect.Height)
+	data.drawFrame(canvas, rect)
+
+	content := data.getContentRect(rect)
+	parentW, parentH := arrangeBase(s.measured, content)
+	constraints := Constraints{
+		MaxWidth:     content.Width,
+		MaxHeight:    content.Height,
+		ParentWidth:  parentW,
+		ParentHeight: parentH,
+	}
+
+	for _, child := range s.Children {
+		if child == nil {
+			continue
+		}
+
+		childW, childH := child.Measure(constraints)
+		x, y := locate(child, parentW, parentH, childW, childH)
+		childRect := Rect{X: content.X + x - 1, Y: content.Y + y - 1, Width: childW, Height: childH}
+
+		arrangeChild(canvas, child, childRect.Intersect(content))
+	}
+}
+
+func (s *ZStack) locate(parentW int, parentH int, width int, height int) (int, int) {
+	return s.boxData(parentW, parentH).getPosition(width, height)
+}
+
+func (s *ZStack) boxData(parentW int, parentH int) *boxData {
+	data := newBoxData(s.Position, s.Dimensions, s.Padding, s.Border, s.Style)
+	data.setParent(parentW, parentH)
+	return data
+}
+
+// Constraints left for a child once part of the content is already taken,
+// relative lengths still resolve against the whole content
+func remainingConstraints(content Constraints, usedW int, usedH int) Constraints {
+	content.ParentWidth, content.ParentHeight = content.parentSize()
+
+	if content.MaxWidth > 0 {
+		content.MaxWidth = max(content.MaxWidth-usedW, 1)
+	}
+
+	if content.MaxHeight > 0 {
+		content.MaxHeight = max(content.MaxHeight-usedH, 1)
+	}
+
+	return content
+}
+
+// Size the relative lengths of the children resolve against when arranging,
+// the one they were measured against, or the content area along axes that
+// were unbounded
+func arrangeBase(measured Constraints, content Rect) (int, int) {
+	width, height := measured.parentSize()
+
+	if width <= 0 {
+		width = content.Width
+	}
+
+	if height <= 0 {
+		height = content.Height
+	}
+
+	return width, height
+}
+
+func arrangeChild(canvas *Matrix, child Component, rect Rect) {
+	if rect.IsEmpty() {
+		return
+	}
+
+	canvas.trackRegion(child, rect)
+	child.Arrange(canvas, rect)
+}
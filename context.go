@@ -1,13 +1,121 @@
 package main
 
+import (
+	"sync"
+	"time"
+)
+
 type Context struct {
 	signals Queue[Signal]
 	refresh bool
 	window  *WindowParams
+
+	// Time between frames, shared by the renderer and the frame ticks
+	frameInterval time.Duration
+
+	// Screen whose code is running, the owner of the timers it starts
+	owner *screenEntry
+	clock Clock
+
+	mutex  sync.Mutex
+	wake   chan struct{}
+	events chan screenEvent
 }
 
 func (c *Context) SendSignal(signal Signal) {
+	c.mutex.Lock()
 	c.signals.Enqueue(signal)
+	c.mutex.Unlock()
+
+	c.notify()
+}
+
+// Asks for the screen to be drawn again. Requests are merged, so calling it
+// many times before the next frame draws only once. Safe to call from any
+// goroutine.
+func (c *Context) RequestRefresh() {
+	c.mutex.Lock()
+	requested := c.refresh
+	c.refresh = true
+	c.mutex.Unlock()
+
+	// A pending request already woke the loop up
+	if !requested {
+		c.notify()
+	}
+}
+
+func (c *Context) clearRefresh() {
+	c.mutex.Lock()
+	c.refresh = false
+	c.mutex.Unlock()
+}
+
+func (c *Context) hasRefresh() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.refresh
+}
+
+// Opens a screen on top of the active one, which is paused until the new one
+// is popped
+func (c *Context) PushScreen(screen Screen) {
+	c.SendSignal(SigPushScreen(screen))
+}
+
+// Closes the active screen, handing the result to the screen below in its
+// OnResume event
+func (c *Context) PopScreen(result any) {
+	c.SendSignal(SigPopScreen(result))
+}
+
+func (c *Context) ReplaceScreen(screen Screen) {
+	c.SendSignal(SigReplaceScreen(screen))
+}
+
+func (c *Context) nextSignal() (Signal, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.signals.IsEmpty() {
+		return nil, false
+	}
+
+	return c.signals.Dequeue(), true
+}
+
+func (c *Context) setOwner(entry *screenEntry) {
+	c.mutex.Lock()
+	c.owner = entry
+	c.mutex.Unlock()
+}
+
+// Delivers an event to the renderer loop, safe to call from any goroutine. The
+// event goes to the given screen, or to the active one when it is nil. It never
+// blocks the caller, which may be the loop itself: when the loop is behind, the
+// event is handed over from another goroutine. Done, when set, is called by
+// the loop once the event is handled.
+func (c *Context) post(entry *screenEntry, event Event, done func()) {
+	posted := screenEvent{entry: entry, event: event, done: done}
+
+	select {
+	case c.events <- posted:
+	default:
+		go func() {
+			defer recoverPanic()
+			c.events <- posted
+		}()
+	}
+}
+
+// Wakes the renderer loop up without delivering anything, pending wake ups
+// are merged into one
+func (c *Context) notify() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
 }
 
 func NewContext(width int, height int) *Context {
@@ -18,9 +126,19 @@ func NewContext(width int, height int) *Context {
 			Width:  width,
 			Height: height,
 		},
+		frameInterval: time.Second / defaultFrameRate,
+		clock:         &realClock{},
+		wake:          make(chan struct{}, 1),
+		events:        make(chan screenEvent, 64),
 	}
 }
 
+type screenEvent struct {
+	entry *screenEntry
+	event Event
+	done  func()
+}
+
 type WindowParams struct {
 	Width  int
 	Height int
@@ -1,5 +1,7 @@
 package main
 
+import "strconv"
+
 const (
 	escClearScreen   = "\033[2J"
 	escMoveCursorTop = "\033[H"
@@ -7,4 +9,26 @@ const (
 	escExitAlternate = "\033[?1049l"
 	escHideCursor    = "\033[?25l"
 	escShowCursor    = "\033[?25h"
+	escResetStyle    = "\033[0m"
+
+	escEnableMouseClick   = "\033[?1000h"
+	escDisableMouseClick  = "\033[?1000l"
+	escEnableMouseButton  = "\033[?1002h"
+	escDisableMouseButton = "\033[?1002l"
+	escEnableMouseAny     = "\033[?1003h"
+	escDisableMouseAny    = "\033[?1003l"
+	escEnableMouseSGR     = "\033[?1006h"
+	escDisableMouseSGR    = "\033[?1006l"
+
+	escEnablePaste  = "\033[?2004h"
+	escDisablePaste = "\033[?2004l"
+	escPasteStart   = "\033[200~"
+	escPasteEnd     = "\033[201~"
+
+	escEnableFocus  = "\033[?1004h"
+	escDisableFocus = "\033[?1004l"
 )
+
+func escMoveCursor(x int, y int) string {
+	return "\033[" + strconv.Itoa(y) + ";" + strconv.Itoa(x) + "H"
+}
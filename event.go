@@ -1,5 +1,7 @@
 package main
 
+import "time"
+
 type Event interface {
 	Payload() map[string]any
 }
@@ -17,3 +19,159 @@ type OnCreate struct {
 func (e *OnCreate) Payload() map[string]any {
 	return nil
 }
+
+type OnKeyPress struct {
+	Key  Key
+	Rune rune
+	Mods KeyMod
+}
+
+func (e *OnKeyPress) Payload() map[string]any {
+	return map[string]any{
+		"key":  e.Key,
+		"rune": e.Rune,
+		"mods": e.Mods,
+	}
+}
+
+type OnResize struct {
+	OldWidth  int
+	OldHeight int
+	Width     int
+	Height    int
+}
+
+func (e *OnResize) Payload() map[string]any {
+	return map[string]any{
+		"oldWidth":  e.OldWidth,
+		"oldHeight": e.OldHeight,
+		"width":     e.Width,
+		"height":    e.Height,
+	}
+}
+
+type OnMouse struct {
+	Button MouseButton
+	Action MouseAction
+	Mods   KeyMod
+	X      int
+	Y      int
+
+	// Component rendered at the position of the mouse, if any
+	Target Component
+}
+
+func (e *OnMouse) Payload() map[string]any {
+	return map[string]any{
+		"button": e.Button,
+		"action": e.Action,
+		"mods":   e.Mods,
+		"x":      e.X,
+		"y":      e.Y,
+		"target": e.Target,
+	}
+}
+
+type OnPaste struct {
+	Text string
+}
+
+func (e *OnPaste) Payload() map[string]any {
+	return map[string]any{
+		"text": e.Text,
+	}
+}
+
+type OnFocusGained struct {
+}
+
+func (e *OnFocusGained) Payload() map[string]any {
+	return nil
+}
+
+type OnFocusLost struct {
+}
+
+func (e *OnFocusLost) Payload() map[string]any {
+	return nil
+}
+
+type OnSuspend struct {
+}
+
+func (e *OnSuspend) Payload() map[string]any {
+	return nil
+}
+
+type OnResume struct {
+	Reason ResumeReason
+
+	// Value the popped screen was closed with
+	Result any
+}
+
+func (e *OnResume) Payload() map[string]any {
+	return map[string]any{
+		"reason": e.Reason,
+		"result": e.Result,
+	}
+}
+
+type OnPause struct {
+}
+
+func (e *OnPause) Payload() map[string]any {
+	return nil
+}
+
+type OnDestroy struct {
+}
+
+func (e *OnDestroy) Payload() map[string]any {
+	return nil
+}
+
+// Delivers signals that have no handler registered in the renderer, which is
+// how application defined signals reach the screen
+type OnSignal struct {
+	Signal Signal
+}
+
+func (e *OnSignal) Payload() map[string]any {
+	return map[string]any{
+		"signal": e.Signal,
+	}
+}
+
+// Posted by the timers started with Context.After and Context.Every, Count is
+// the number of times the timer has fired
+type OnTimer struct {
+	Timer *Timer
+	Time  time.Time
+	Count int
+}
+
+func (e *OnTimer) Payload() map[string]any {
+	return map[string]any{
+		"timer": e.Timer,
+		"time":  e.Time,
+		"count": e.Count,
+	}
+}
+
+// Posted every frame by the timers started with Context.FrameTick
+type OnTick struct {
+	Timer *Timer
+	Time  time.Time
+	Delta time.Duration
+	Frame int
+}
+
+func (e *OnTick) Payload() map[string]any {
+	return map[string]any{
+		"timer": e.Timer,
+		"time":  e.Time,
+		"delta": e.Delta,
+		"frame": e.Frame,
+	}
+}
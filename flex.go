@@ -0,0 +1,499 @@
+package main
+
+import "math"
+
+type FlexDirection int
+type FlexWrap int
+type Justify int
+type Align int
+
+const (
+	FlexRow FlexDirection = iota
+	FlexColumn
+)
+
+const (
+	FlexNoWrap FlexWrap = iota
+	FlexWrapLines
+)
+
+const (
+	JustifyStart Justify = iota
+	JustifyEnd
+	JustifyCenter
+	JustifySpaceBetween
+	JustifySpaceAround
+	JustifySpaceEvenly
+)
+
+const (
+	AlignStretch Align = iota
+	AlignStart
+	AlignCenter
+	AlignEnd
+)
+
+// Basis value that makes an item start from the size of its content
+const FlexAuto = -1
+
+type FlexItem struct {
+	child     Component
+	grow      float64
+	shrink    float64
+	basis     int
+	minWidth  int
+	minHeight int
+	maxWidth  int
+	maxHeight int
+}
+
+// Sets the smallest size the item can be shrunk to, 0 means no limit
+func (i *FlexItem) WithMin(width int, height int) *FlexItem {
+	i.minWidth = defaultToZero(width)
+	i.minHeight = defaultToZero(height)
+	return i
+}
+
+// Sets the largest size the item can be grown to, 0 means no limit
+func (i *FlexItem) WithMax(width int, height int) *FlexItem {
+	i.maxWidth = defaultToZero(width)
+	i.maxHeight = defaultToZero(height)
+	return i
+}
+
+func (i *FlexItem) clampWidth(width int) int {
+	return clampSize(width, i.minWidth, i.maxWidth)
+}
+
+func (i *FlexItem) clampHeight(height int) int {
+	return clampSize(height, i.minHeight, i.maxHeight)
+}
+
+// Size the item wants within the space of the container
+func (i *FlexItem) measure(constraints Constraints) (int, int) {
+	if i.child == nil {
+		return 0, 0
+	}
+
+	return i.child.Measure(constraints)
+}
+
+func NewFlexItem(child Component, grow float64, shrink float64, basis int) *FlexItem {
+	if grow < 0 {
+		grow = 0
+	}
+
+	if shrink < 0 {
+		shrink = 0
+	}
+
+	if basis < 0 {
+		basis = FlexAuto
+	}
+
+	return &FlexItem{child: child, grow: grow, shrink: shrink, basis: basis}
+}
+
+type flexRect struct {
+	x      int
+	y      int
+	width  int
+	height int
+}
+
+// Container that sizes and places its items following the flexbox model.
+// Measuring reports the size the items want, and arranging distributes the
+// area the container was given among them.
+type Flex struct {
+	Items      []*FlexItem
+	Direction  FlexDirection
+	Wrap       FlexWrap
+	Justify    Justify
+	AlignItems Align
+	Gap        int
+	Position   *Position
+	Dimensions *Dimensions
+	Padding    *Padding
+	Border     *Border
+	Style      *Style
+}
+
+func (f *Flex) Render() (*Matrix, int, int) {
+	return renderComponent(f)
+}
+
+// Size of the container when every item takes its preferred size, axes with
+// a fixed size keep it
+func (f *Flex) Measure(constraints Constraints) (int, int) {
+	data := f.boxData(constraints.parentSize())
+	data.constrain(constraints)
+	content := data.getContentConstraints()
+	gap := defaultToZero(f.Gap)
+	main := 0
+	cross := 0
+	count := 0
+
+	for _, item := range f.Items {
+		if item == nil {
+			continue
+		}
+
+		w, h := item.measure(content)
+		itemMain, itemCross := f.toMainCross(item.clampWidth(w), item.clampHeight(h))
+
+		if item.basis != FlexAuto {
+			itemMain = f.clampMain(item, item.basis)
+		}
+
+		main += itemMain
+		cross = max(cross, itemCross)
+		count++
+	}
+
+	if count > 1 {
+		main += gap * (count - 1)
+	}
+
+	return constraints.Clamp(data.measureFrame(f.fromMainCross(main, cross)))
+}
+
+func (f *Flex) Arrange(canvas *Matrix, rect Rect) {
+	data := f.boxData(rect.Width, rect.Height)
+	data.drawFrame(canvas, rect)
+
+	content := data.getContentRect(rect)
+	if content.IsEmpty() {
+		return
+	}
+
+	main, cross := f.toMainCross(content.Width, content.Height)
+	constraints := Constraints{MaxWidth: content.Width, MaxHeight: content.Height}
+	rects := f.layoutItems(main, cross, constraints)
+
+	for i, item := range f.Items {
+		if item == nil || item.child == nil {
+			continue
+		}
+
+		itemRect := Rect{
+			X:      content.X + rects[i].x - 1,
+			Y:      content.Y + rects[i].y - 1,
+			Width:  rects[i].width,
+			Height: rects[i].height,
+		}
+
+		// Items that could not shrink enough overflow the content area
+		arrangeChild(canvas, item.child, itemRect.Intersect(content))
+	}
+}
+
+func (f *Flex) locate(parentW int, parentH int, width int, height int) (int, int) {
+	return f.boxData(parentW, parentH).getPosition(width, height)
+}
+
+func (f *Flex) boxData(parentW int, parentH int) *boxData {
+	data := newBoxData(f.Position, f.Dimensions, f.Padding, f.Border, f.Style)
+	data.setParent(parentW, parentH)
+	return data
+}
+
+func (f *Flex) layoutItems(mainSize int, crossSize int, constraints Constraints) []flexRect {
+	count := len(f.Items)
+	rects := make([]flexRect, count)
+	gap := defaultToZero(f.Gap)
+
+	hypothetical := make([]int, count)
+	crossSizes := make([]int, count)
+
+	for i, item := range f.Items {
+		if item == nil {
+			continue
+		}
+
+		w, h := item.measure(constraints)
+		itemMain, itemCross := f.toMainCross(w, h)
+
+		if item.basis != FlexAuto {
+			itemMain = item.basis
+		}
+
+		hypothetical[i] = f.clampMain(item, itemMain)
+		crossSizes[i] = f.clampCross(item, itemCross)
+	}
+
+	lines := f.breakLines(hypothetical, mainSize, gap)
+	crossOffset := 1
+
+	for _, line := range lines {
+		sizes := f.resolveFlexibleSizes(line, hypothetical, mainSize, gap)
+
+		lineCross := 0
+		for _, i := range line {
+			lineCross = max(lineCross, crossSizes[i])
+		}
+
+		// A single line takes the whole cross axis, so items can be aligned
+		// or stretched across it
+		if len(lines) == 1 {
+			lineCross = crossSize
+		}
+
+		used := gap * (len(line) - 1)
+		for _, size := range sizes {
+			used += size
+		}
+
+		spacing := justifySpacing(f.Justify, mainSize-used, len(line))
+		mainOffset := 1
+
+		for k, i := range line {
+			item := f.Items[i]
+			itemCross := min(crossSizes[i], lineCross)
+			crossPos := 0
+
+			switch f.AlignItems {
+			case AlignStretch:
+				itemCross = min(f.clampCross(item, lineCross), lineCross)
+			case AlignCenter:
+				crossPos = (lineCross - itemCross) / 2
+			case AlignEnd:
+				crossPos = lineCross - itemCross
+			}
+
+			mainOffset += spacing[k]
+
+			x, y := f.fromMainCross(mainOffset, crossOffset+crossPos)
+			width, height := f.fromMainCross(sizes[k], itemCross)
+			rects[i] = flexRect{x: x, y: y, width: width, height: height}
+
+			mainOffset += sizes[k] + gap
+		}
+
+		crossOffset += lineCross + gap
+	}
+
+	return rects
+}
+
+// Splits the items into lines, starting a new line whenever the next item
+// does not fit in the main axis
+func (f *Flex) breakLines(sizes []int, mainSize int, gap int) [][]int {
+	lines := [][]int{}
+	line := []int{}
+	used := 0
+
+	for i, item := range f.Items {
+		if item == nil {
+			continue
+		}
+
+		needed := sizes[i]
+		if len(line) > 0 {
+			needed += gap
+		}
+
+		if f.Wrap == FlexWrapLines && len(line) > 0 && used+needed > mainSize {
+			lines = append(lines, line)
+			line = []int{}
+			used = 0
+			needed = sizes[i]
+		}
+
+		line = append(line, i)
+		used += needed
+	}
+
+	if len(line) > 0 {
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// Grows or shrinks the items of a line so they fill the main axis. Items that
+// hit their min or max size are frozen and the rest of the space is shared
+// again among the others.
+func (f *Flex) resolveFlexibleSizes(line []int, hypothetical []int, mainSize int, gap int) []int {
+	sizes := make([]int, len(line))
+	frozen := make([]bool, len(line))
+
+	for k, i := range line {
+		sizes[k] = hypothetical[i]
+	}
+
+	for range line {
+		free := mainSize - gap*(len(line)-1)
+		for _, size := range sizes {
+			free -= size
+		}
+
+		if free == 0 {
+			break
+		}
+
+		weights := make([]float64, len(line))
+		total := 0.0
+
+		for k, i := range line {
+			if frozen[k] {
+				continue
+			}
+
+			item := f.Items[i]
+
+			if free > 0 {
+				weights[k] = item.grow
+			} else {
+				// Shrinking is scaled by the size, so bigger items give
+				// away more space
+				weights[k] = item.shrink * float64(hypothetical[i])
+			}
+
+			total += weights[k]
+		}
+
+		if total == 0 {
+			break
+		}
+
+		shares := spread(free, weights)
+		clamped := false
+
+		for k, i := range line {
+			if frozen[k] || weights[k] == 0 {
+				continue
+			}
+
+			target := max(sizes[k]+shares[k], 0)
+			size := f.clampMain(f.Items[i], target)
+
+			if size != target {
+				frozen[k] = true
+				clamped = true
+			}
+
+			sizes[k] = size
+		}
+
+		if !clamped {
+			break
+		}
+	}
+
+	return sizes
+}
+
+func (f *Flex) clampMain(item *FlexItem, size int) int {
+	if f.Direction == FlexColumn {
+		return item.clampHeight(size)
+	}
+	return item.clampWidth(size)
+}
+
+func (f *Flex) clampCross(item *FlexItem, size int) int {
+	if f.Direction == FlexColumn {
+		return item.clampWidth(size)
+	}
+	return item.clampHeight(size)
+}
+
+func (f *Flex) toMainCross(width int, height int) (int, int) {
+	if f.Direction == FlexColumn {
+		return height, width
+	}
+	return width, height
+}
+
+func (f *Flex) fromMainCross(main int, cross int) (int, int) {
+	if f.Direction == FlexColumn {
+		return cross, main
+	}
+	return main, cross
+}
+
+// Returns the space to leave before each item so the free space is
+// distributed according to the justification
+func justifySpacing(justify Justify, free int, count int) []int {
+	spacing := make([]int, count)
+
+	if free <= 0 || count == 0 {
+		return spacing
+	}
+
+	weights := make([]float64, count)
+
+	switch justify {
+	case JustifyEnd:
+		weights[0] = 1
+	case JustifyCenter:
+		spacing[0] = free / 2
+		return spacing
+	case JustifySpaceBetween:
+		if count == 1 {
+			return spacing
+		}
+
+		for i := 1; i < count; i++ {
+			weights[i] = 1
+		}
+	case JustifySpaceAround:
+		// Each item has the same space on both of its sides, so the space
+		// between two items is twice the one at the edges
+		weights[0] = 1
+		for i := 1; i < count; i++ {
+			weights[i] = 2
+		}
+
+		// The trailing edge is not part of the spacing, it is what is left
+		free -= int(math.Round(float64(free) / float64(2*count)))
+	case JustifySpaceEvenly:
+		for i := range weights {
+			weights[i] = 1
+		}
+
+		free -= free / (count + 1)
+	default:
+		return spacing
+	}
+
+	return spread(free, weights)
+}
+
+// Splits an amount proportionally to the weights, rounding so the parts always
+// add up to the total
+func spread(total int, weights []float64) []int {
+	parts := make([]int, len(weights))
+	sum := 0.0
+
+	for _, w := range weights {
+		sum += w
+	}
+
+	if sum == 0 {
+		return parts
+	}
+
+	accumulated := 0.0
+	previous := 0
+
+	for i, w := range weights {
+		accumulated += float64(total) * w / sum
+		current := int(math.Round(accumulated))
+		parts[i] = current - previous
+		previous = current
+	}
+
+	return parts
+}
+
+func clampSize(size int, minimum int, maximum int) int {
+	if maximum > 0 && size > maximum {
+		size = maximum
+	}
+
+	if size < minimum {
+		size = minimum
+	}
+
+	return size
+}
@@ -2,4 +2,7 @@ module github.com/fueripe-desu/bkalpha
 
 go 1.22.3
 
-require golang.org/x/sys v0.25.0 // indirect
+require (
+	github.com/rivo/uniseg v0.4.7
+	golang.org/x/sys v0.25.0
+)
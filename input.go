@@ -0,0 +1,439 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Time to wait for the rest of an escape sequence before a lone ESC is
+// reported as the escape key
+const defaultEscTimeout = 50 * time.Millisecond
+
+// Longest CSI sequence accepted before the bytes are discarded as garbage
+const maxCSILength = 64
+
+// Longest paste and longest wait for its end marker before what arrived so far
+// is delivered as the paste, so a lost marker can't swallow all later input
+const maxPasteLength = 1 << 20
+const pasteTimeout = time.Second
+
+type InputReader struct {
+	file       *os.File
+	events     chan Event
+	escTimeout time.Duration
+	started    bool
+
+	// Bytes of the pending paste already searched for the end marker, and
+	// when the paste began
+	pasteScanned int
+	pasteStarted time.Time
+}
+
+func (r *InputReader) Events() <-chan Event {
+	return r.events
+}
+
+func (r *InputReader) Start() {
+	if r.started {
+		return
+	}
+
+	r.started = true
+	raw := make(chan []byte)
+
+	go r.readLoop(raw)
+	go r.decodeLoop(raw)
+}
+
+func (r *InputReader) readLoop(raw chan<- []byte) {
+	defer recoverPanic()
+
+	buffer := make([]byte, 256)
+
+	for {
+		n, err := r.file.Read(buffer)
+
+		if n > 0 {
+			chunk := make([]byte, n)
+			copy(chunk, buffer[:n])
+			raw <- chunk
+		}
+
+		if err != nil {
+			close(raw)
+			return
+		}
+	}
+}
+
+func (r *InputReader) decodeLoop(raw <-chan []byte) {
+	defer recoverPanic()
+
+	var pending []byte
+	var timeout <-chan time.Time
+
+	for {
+		select {
+		case chunk, ok := <-raw:
+			if !ok {
+				r.dispatch(pending, true)
+				close(r.events)
+				return
+			}
+
+			pending = r.dispatch(append(pending, chunk...), false)
+		case <-timeout:
+			// Nothing else arrived, so whatever is left is taken literally
+			pending = r.dispatch(pending, true)
+		}
+
+		if len(pending) > 0 {
+			timeout = time.After(r.escTimeout)
+		} else {
+			timeout = nil
+		}
+	}
+}
+
+func (r *InputReader) dispatch(pending []byte, flush bool) []byte {
+	for len(pending) > 0 {
+		var event Event
+		var n int
+
+		if bytes.HasPrefix(pending, []byte(escPasteStart)) {
+			event, n = r.decodePaste(pending, flush)
+		} else {
+			event, n = decodeInput(pending, flush)
+		}
+
+		if n == 0 {
+			break
+		}
+
+		if event != nil {
+			r.events <- event
+		}
+
+		pending = pending[n:]
+	}
+
+	return pending
+}
+
+// Decodes a paste that can span many reads, resuming the end marker search
+// where the last read left it and giving up once the paste is too slow
+func (r *InputReader) decodePaste(buf []byte, flush bool) (Event, int) {
+	if r.pasteStarted.IsZero() {
+		r.pasteStarted = time.Now()
+	}
+
+	expired := flush && time.Since(r.pasteStarted) >= pasteTimeout
+	event, n := decodePaste(buf, r.pasteScanned, expired)
+
+	if n == 0 {
+		r.pasteScanned = len(buf) - len(escPasteStart)
+		return nil, 0
+	}
+
+	r.pasteScanned = 0
+	r.pasteStarted = time.Time{}
+
+	return event, n
+}
+
+func NewInputReader(file *os.File) *InputReader {
+	return &InputReader{
+		file:       file,
+		events:     make(chan Event, 64),
+		escTimeout: defaultEscTimeout,
+	}
+}
+
+// Decodes the first event at the start of the buffer, returning it along with
+// the number of bytes consumed. A zero length means the buffer holds an
+// incomplete sequence and more bytes are needed, unless flush is set, in which
+// case the bytes are interpreted as they are. Unknown sequences are consumed
+// with a nil event.
+func decodeInput(buf []byte, flush bool) (Event, int) {
+	if len(buf) == 0 {
+		return nil, 0
+	}
+
+	if buf[0] != 0x1b {
+		return decodeKey(buf, flush)
+	}
+
+	if len(buf) == 1 {
+		if flush {
+			return newKeyPress(KeyEsc, 0, ModNone), 1
+		}
+		return nil, 0
+	}
+
+	var event Event
+	var n int
+
+	if bytes.HasPrefix(buf, []byte(escPasteStart)) {
+		return decodePaste(buf, 0, false)
+	}
+
+	switch buf[1] {
+	case '[':
+		event, n = decodeCSI(buf)
+	case 'O':
+		event, n = decodeSS3(buf)
+	default:
+		// ESC followed by a key means the key was pressed with Alt
+		event, n = decodeInput(buf[1:], flush)
+
+		if n > 0 {
+			if key, ok := event.(*OnKeyPress); ok {
+				key.Mods |= ModAlt
+			}
+			n++
+		}
+	}
+
+	if n == 0 && flush {
+		// Nothing completed the sequence, so ESC [ and ESC O were Alt with
+		// the bracket or the letter
+		if len(buf) == 2 && (buf[1] == '[' || buf[1] == 'O') {
+			return newKeyPress(KeyRune, rune(buf[1]), ModAlt), 2
+		}
+
+		return newKeyPress(KeyEsc, 0, ModNone), 1
+	}
+
+	return event, n
+}
+
+func decodeKey(buf []byte, flush bool) (Event, int) {
+	b := buf[0]
+
+	switch {
+	case b == '\r' || b == '\n':
+		return newKeyPress(KeyEnter, 0, ModNone), 1
+	case b == '\t':
+		return newKeyPress(KeyTab, 0, ModNone), 1
+	case b == 0x7f:
+		return newKeyPress(KeyBackspace, 0, ModNone), 1
+	case b == 0x08:
+		return newKeyPress(KeyBackspace, 0, ModCtrl), 1
+	case b == 0x00:
+		return newKeyPress(KeyRune, ' ', ModCtrl), 1
+	case b >= 0x01 && b <= 0x1a:
+		return newKeyPress(KeyRune, rune('a'+b-1), ModCtrl), 1
+	case b >= 0x1c && b <= 0x1f:
+		return newKeyPress(KeyRune, rune('\\'+b-0x1c), ModCtrl), 1
+	case b == 0x1b:
+		return newKeyPress(KeyEsc, 0, ModNone), 1
+	}
+
+	if !utf8.FullRune(buf) {
+		if flush {
+			return newKeyPress(KeyRune, utf8.RuneError, ModNone), 1
+		}
+		return nil, 0
+	}
+
+	r, size := utf8.DecodeRune(buf)
+	return newKeyPress(KeyRune, r, ModNone), size
+}
+
+// Control Sequence Introducer: ESC [ params final
+func decodeCSI(buf []byte) (Event, int) {
+	end := -1
+
+	for i := 2; i < len(buf); i++ {
+		b := buf[i]
+
+		if b >= 0x40 && b <= 0x7e {
+			end = i
+			break
+		}
+
+		if b < 0x20 || b > 0x3f {
+			// Not a valid sequence, discard what has been read
+			return nil, i
+		}
+	}
+
+	if end == -1 {
+		if len(buf) > maxCSILength {
+			return nil, len(buf)
+		}
+		return nil, 0
+	}
+
+	final := buf[end]
+	n := end + 1
+
+	if buf[2] == '<' && (final == 'M' || final == 'm') {
+		return decodeSGRMouse(parseCSIParams(string(buf[3:end])), final), n
+	}
+
+	params := parseCSIParams(string(buf[2:end]))
+
+	mods := ModNone
+	if len(params) > 1 {
+		mods = keyModFromParam(params[1])
+	}
+
+	switch final {
+	case 'A':
+		return newKeyPress(KeyUp, 0, mods), n
+	case 'B':
+		return newKeyPress(KeyDown, 0, mods), n
+	case 'C':
+		return newKeyPress(KeyRight, 0, mods), n
+	case 'D':
+		return newKeyPress(KeyLeft, 0, mods), n
+	case 'H':
+		return newKeyPress(KeyHome, 0, mods), n
+	case 'F':
+		return newKeyPress(KeyEnd, 0, mods), n
+	case 'P':
+		return newKeyPress(KeyF1, 0, mods), n
+	case 'Q':
+		return newKeyPress(KeyF2, 0, mods), n
+	case 'R':
+		return newKeyPress(KeyF3, 0, mods), n
+	case 'S':
+		return newKeyPress(KeyF4, 0, mods), n
+	case 'Z':
+		return newKeyPress(KeyBacktab, 0, ModShift), n
+	case 'I':
+		return &OnFocusGained{}, n
+	case 'O':
+		return &OnFocusLost{}, n
+	case '~':
+		if len(params) == 0 {
+			return nil, n
+		}
+
+		key, ok := tildeKeys[params[0]]
+		if !ok {
+			return nil, n
+		}
+
+		return newKeyPress(key, 0, mods), n
+	}
+
+	return nil, n
+}
+
+// Single Shift Three: ESC O final, sent by some terminals for arrows and F1-F4
+func decodeSS3(buf []byte) (Event, int) {
+	if len(buf) < 3 {
+		return nil, 0
+	}
+
+	switch buf[2] {
+	case 'A':
+		return newKeyPress(KeyUp, 0, ModNone), 3
+	case 'B':
+		return newKeyPress(KeyDown, 0, ModNone), 3
+	case 'C':
+		return newKeyPress(KeyRight, 0, ModNone), 3
+	case 'D':
+		return newKeyPress(KeyLeft, 0, ModNone), 3
+	case 'H':
+		return newKeyPress(KeyHome, 0, ModNone), 3
+	case 'F':
+		return newKeyPress(KeyEnd, 0, ModNone), 3
+	case 'P':
+		return newKeyPress(KeyF1, 0, ModNone), 3
+	case 'Q':
+		return newKeyPress(KeyF2, 0, ModNone), 3
+	case 'R':
+		return newKeyPress(KeyF3, 0, ModNone), 3
+	case 'S':
+		return newKeyPress(KeyF4, 0, ModNone), 3
+	}
+
+	return nil, 3
+}
+
+// Collects everything between the bracketed paste markers into one event,
+// searching for the end marker from the given offset into the paste. The
+// paste is waited for even when flushing, since a long one can take a while
+// to arrive, unless it expired or grew past the limit, in which case the
+// whole buffer is taken as the paste.
+func decodePaste(buf []byte, from int, expired bool) (Event, int) {
+	content := buf[len(escPasteStart):]
+
+	// The end marker may have been cut in half by the previous read
+	start := min(max(from-len(escPasteEnd)+1, 0), len(content))
+	end := bytes.Index(content[start:], []byte(escPasteEnd))
+
+	if end == -1 {
+		if !expired && len(content) < maxPasteLength {
+			return nil, 0
+		}
+
+		return newPaste(content), len(buf)
+	}
+
+	end += start
+	return newPaste(content[:end]), len(escPasteStart) + end + len(escPasteEnd)
+}
+
+func newPaste(content []byte) *OnPaste {
+	// Terminals send carriage returns for the line breaks in a paste
+	text := strings.ReplaceAll(string(content), "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	return &OnPaste{Text: text}
+}
+
+var tildeKeys = map[int]Key{
+	1:  KeyHome,
+	2:  KeyInsert,
+	3:  KeyDelete,
+	4:  KeyEnd,
+	5:  KeyPageUp,
+	6:  KeyPageDown,
+	7:  KeyHome,
+	8:  KeyEnd,
+	11: KeyF1,
+	12: KeyF2,
+	13: KeyF3,
+	14: KeyF4,
+	15: KeyF5,
+	17: KeyF6,
+	18: KeyF7,
+	19: KeyF8,
+	20: KeyF9,
+	21: KeyF10,
+	23: KeyF11,
+	24: KeyF12,
+}
+
+func parseCSIParams(raw string) []int {
+	if raw == "" {
+		return nil
+	}
+
+	parts := strings.Split(raw, ";")
+	params := make([]int, len(parts))
+
+	for i, p := range parts {
+		value, err := strconv.Atoi(p)
+
+		if err != nil {
+			value = 0
+		}
+
+		params[i] = value
+	}
+
+	return params
+}
+
+func newKeyPress(key Key, r rune, mods KeyMod) *OnKeyPress {
+	return &OnKeyPress{Key: key, Rune: r, Mods: mods}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodeInput(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		flush bool
+		event Event
+		n     int
+	}{
+		{"rune", "a", false, newKeyPress(KeyRune, 'a', ModNone), 1},
+		{"utf-8 rune", "é", false, newKeyPress(KeyRune, 'é', ModNone), 2},
+		{"enter", "\r", false, newKeyPress(KeyEnter, 0, ModNone), 1},
+		{"tab", "\t", false, newKeyPress(KeyTab, 0, ModNone), 1},
+		{"backspace", "\x7f", false, newKeyPress(KeyBackspace, 0, ModNone), 1},
+		{"ctrl rune", "\x01", false, newKeyPress(KeyRune, 'a', ModCtrl), 1},
+		{"ctrl space", "\x00", false, newKeyPress(KeyRune, ' ', ModCtrl), 1},
+
+		{"csi arrow", "\x1b[A", false, newKeyPress(KeyUp, 0, ModNone), 3},
+		{"csi shift arrow", "\x1b[1;2A", false, newKeyPress(KeyUp, 0, ModShift), 6},
+		{"csi ctrl arrow", "\x1b[1;5C", false, newKeyPress(KeyRight, 0, ModCtrl), 6},
+		{"csi ctrl alt arrow", "\x1b[1;7D", false, newKeyPress(KeyLeft, 0, ModCtrl|ModAlt), 6},
+		{"csi home", "\x1b[H", false, newKeyPress(KeyHome, 0, ModNone), 3},
+		{"csi backtab", "\x1b[Z", false, newKeyPress(KeyBacktab, 0, ModShift), 3},
+		{"csi tilde key", "\x1b[3~", false, newKeyPress(KeyDelete, 0, ModNone), 4},
+		{"csi tilde key with mods", "\x1b[15;3~", false, newKeyPress(KeyF5, 0, ModAlt), 7},
+		{"csi unknown tilde key", "\x1b[99~", false, nil, 5},
+		{"csi focus gained", "\x1b[I", false, &OnFocusGained{}, 3},
+		{"csi focus lost", "\x1b[O", false, &OnFocusLost{}, 3},
+		{"csi sgr mouse", "\x1b[<0;10;5M", false, &OnMouse{Button: MouseLeft, Action: MousePress, X: 10, Y: 5}, 10},
+		{"csi sgr mouse release", "\x1b[<2;3;4m", false, &OnMouse{Button: MouseRight, Action: MouseRelease, X: 3, Y: 4}, 9},
+
+		{"ss3 arrow", "\x1bOA", false, newKeyPress(KeyUp, 0, ModNone), 3},
+		{"ss3 function key", "\x1bOP", false, newKeyPress(KeyF1, 0, ModNone), 3},
+
+		{"alt rune", "\x1ba", false, newKeyPress(KeyRune, 'a', ModAlt), 2},
+		{"alt shifted rune", "\x1bA", false, newKeyPress(KeyRune, 'A', ModAlt), 2},
+		{"alt ctrl rune", "\x1b\x01", false, newKeyPress(KeyRune, 'a', ModCtrl|ModAlt), 2},
+		{"alt enter", "\x1b\r", false, newKeyPress(KeyEnter, 0, ModAlt), 2},
+		{"alt bracket at timeout", "\x1b[", true, newKeyPress(KeyRune, '[', ModAlt), 2},
+		{"alt o at timeout", "\x1bO", true, newKeyPress(KeyRune, 'O', ModAlt), 2},
+
+		{"lone esc waits", "\x1b", false, nil, 0},
+		{"lone esc at timeout", "\x1b", true, newKeyPress(KeyEsc, 0, ModNone), 1},
+		{"split csi waits", "\x1b[1;5", false, nil, 0},
+		{"split ss3 waits", "\x1bO", false, nil, 0},
+		{"split csi at timeout", "\x1b[1;5", true, newKeyPress(KeyEsc, 0, ModNone), 1},
+		{"split utf-8 waits", "\xc3", false, nil, 0},
+		{"split utf-8 at timeout", "\xc3", true, newKeyPress(KeyRune, '�', ModNone), 1},
+		{"first of many", "ab", false, newKeyPress(KeyRune, 'a', ModNone), 1},
+
+		{"paste", "\x1b[200~hi\r\nyou\x1b[201~", false, &OnPaste{Text: "hi\nyou"}, 19},
+		{"split paste waits", "\x1b[200~hi", true, nil, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			event, n := decodeInput([]byte(test.input), test.flush)
+
+			if n != test.n {
+				t.Errorf("consumed %d bytes, want %d", n, test.n)
+			}
+
+			if !reflect.DeepEqual(event, test.event) {
+				t.Errorf("decoded %#v, want %#v", event, test.event)
+			}
+		})
+	}
+}
+
+func TestDispatchSplitReads(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+		events []Event
+	}{
+		{
+			name:   "csi across reads",
+			chunks: []string{"\x1b[1;", "5A"},
+			events: []Event{newKeyPress(KeyUp, 0, ModCtrl)},
+		},
+		{
+			name:   "esc then sequence",
+			chunks: []string{"\x1b", "[B", "x"},
+			events: []Event{newKeyPress(KeyDown, 0, ModNone), newKeyPress(KeyRune, 'x', ModNone)},
+		},
+		{
+			name:   "utf-8 across reads",
+			chunks: []string{"\xe6\x97", "\xa5"},
+			events: []Event{newKeyPress(KeyRune, '日', ModNone)},
+		},
+		{
+			name:   "paste end marker across reads",
+			chunks: []string{"\x1b[200~ab", "c\x1b[2", "01~x"},
+			events: []Event{&OnPaste{Text: "abc"}, newKeyPress(KeyRune, 'x', ModNone)},
+		},
+		{
+			name:   "keys around a sequence",
+			chunks: []string{"a\x1b[", "Cb"},
+			events: []Event{
+				newKeyPress(KeyRune, 'a', ModNone),
+				newKeyPress(KeyRight, 0, ModNone),
+				newKeyPress(KeyRune, 'b', ModNone),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reader := &InputReader{events: make(chan Event, 16)}
+			var pending []byte
+
+			for _, chunk := range test.chunks {
+				pending = reader.dispatch(append(pending, chunk...), false)
+			}
+
+			if len(pending) != 0 {
+				t.Errorf("left %q undecoded", pending)
+			}
+
+			close(reader.events)
+			events := []Event{}
+
+			for event := range reader.events {
+				events = append(events, event)
+			}
+
+			if !reflect.DeepEqual(events, test.events) {
+				t.Errorf("decoded %#v, want %#v", events, test.events)
+			}
+		})
+	}
+}
+
+func TestDispatchPasteLimits(t *testing.T) {
+	long := strings.Repeat("a", maxPasteLength)
+
+	reader := &InputReader{events: make(chan Event, 16)}
+	pending := reader.dispatch([]byte(escPasteStart+long), false)
+
+	if len(pending) != 0 {
+		t.Errorf("left %d bytes of an oversized paste undecoded", len(pending))
+	}
+
+	if event := <-reader.events; !reflect.DeepEqual(event, &OnPaste{Text: long}) {
+		t.Errorf("oversized paste decoded as %T", event)
+	}
+
+	reader.dispatch([]byte(escPasteStart+"lost"), false)
+	reader.pasteStarted = time.Now().Add(-pasteTimeout)
+	pending = reader.dispatch([]byte(escPasteStart+"lost"), true)
+
+	if len(pending) != 0 {
+		t.Errorf("left %q of an expired paste undecoded", pending)
+	}
+
+	if event := <-reader.events; !reflect.DeepEqual(event, &OnPaste{Text: "lost"}) {
+		t.Errorf("expired paste decoded as %#v", event)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+type Key int
+type KeyMod int
+
+const (
+	KeyRune Key = iota
+	KeyEnter
+	KeyTab
+	KeyBacktab
+	KeyBackspace
+	KeyEsc
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyHome
+	KeyEnd
+	KeyPageUp
+	KeyPageDown
+	KeyInsert
+	KeyDelete
+	KeyF1
+	KeyF2
+	KeyF3
+	KeyF4
+	KeyF5
+	KeyF6
+	KeyF7
+	KeyF8
+	KeyF9
+	KeyF10
+	KeyF11
+	KeyF12
+)
+
+const (
+	ModNone  KeyMod = 0
+	ModShift KeyMod = 1 << iota
+	ModAlt
+	ModCtrl
+)
+
+func (m KeyMod) Has(mod KeyMod) bool {
+	return m&mod != 0
+}
+
+// Converts the modifier parameter used by xterm style sequences
+// (1 + shift + 2*alt + 4*ctrl) into a KeyMod
+func keyModFromParam(param int) KeyMod {
+	if param <= 1 {
+		return ModNone
+	}
+
+	bits := param - 1
+	mods := ModNone
+
+	if bits&1 != 0 {
+		mods |= ModShift
+	}
+
+	if bits&2 != 0 {
+		mods |= ModAlt
+	}
+
+	if bits&4 != 0 {
+		mods |= ModCtrl
+	}
+
+	return mods
+}
@@ -1,23 +1,21 @@
 package main
 
-import (
-	"os"
-)
-
 type MainScreen struct {
 }
 
-func (s *MainScreen) OnEvent(ctx *Context, event Event) {
+func (s *MainScreen) OnEvent(ctx *Context, event Event) Cmd {
 	switch event.(type) {
 	case *OnWindowCreate:
 	case *OnCreate:
+	case *OnKeyPress:
+		ctx.SendSignal(SigExit)
 	}
+
+	return nil
 }
 
-func (s *MainScreen) Update(ctx *Context) {
-	var b = make([]byte, 1)
-	os.Stdin.Read(b)
-	ctx.SendSignal(SigExit)
+func (s *MainScreen) Update(ctx *Context) Cmd {
+	return nil
 }
 
 func (s *MainScreen) View(ctx *Context) Component {
@@ -1,22 +1,25 @@
 package main
 
 import (
-	"log"
 	"strings"
 )
 
-type ElementCallback = func(colIndex int, rowIndex int, element rune, end bool) rune
+type ElementCallback = func(colIndex int, rowIndex int, element Cell, end bool) Cell
 
 type Matrix struct {
-	data   [][]rune
+	data   [][]Cell
 	width  int
 	height int
+
+	// Areas components were arranged into, only recorded when tracking
+	tracking bool
+	regions  []componentRegion
 }
 
 func (m *Matrix) Disnulify() {
-	m.ForEach(func(colIndex int, rowIndex int, element rune, end bool) rune {
-		if element == rune(0) {
-			return rune(' ')
+	m.ForEach(func(colIndex int, rowIndex int, element Cell, end bool) Cell {
+		if element.Rune == rune(0) {
+			element.Rune = rune(' ')
 		}
 
 		return element
@@ -24,11 +27,49 @@ func (m *Matrix) Disnulify() {
 }
 
 func (m *Matrix) Clear() {
-	m.ForEach(func(colIndex int, rowIndex int, element rune, end bool) rune {
-		return rune(' ')
+	m.ForEach(func(colIndex int, rowIndex int, element Cell, end bool) Cell {
+		return blankCell()
+	})
+}
+
+func (m *Matrix) Fill(cell Cell) {
+	m.ForEach(func(colIndex int, rowIndex int, element Cell, end bool) Cell {
+		return cell
 	})
 }
 
+func (m *Matrix) Clone() *Matrix {
+	data := make([][]Cell, len(m.data))
+
+	for i, row := range m.data {
+		data[i] = make([]Cell, len(row))
+		copy(data[i], row)
+	}
+
+	return &Matrix{
+		data:   data,
+		width:  m.width,
+		height: m.height,
+	}
+}
+
+// Starts recording the area each component is arranged into from scratch, so
+// positions can be mapped back to components
+func (m *Matrix) TrackRegions() {
+	m.tracking = true
+	m.regions = m.regions[:0]
+}
+
+func (m *Matrix) ComponentAt(x int, y int) Component {
+	return hitTest(m.regions, x, y)
+}
+
+func (m *Matrix) trackRegion(component Component, rect Rect) {
+	if m.tracking {
+		m.regions = append(m.regions, componentRegion{component: component, rect: rect})
+	}
+}
+
 func (m *Matrix) Height() int {
 	return m.height
 }
@@ -37,31 +78,31 @@ func (m *Matrix) Width() int {
 	return m.width
 }
 
-func (m *Matrix) Get(col int, row int) rune {
+func (m *Matrix) Get(col int, row int) Cell {
 	if col > m.height || col < 1 {
-		log.Fatal("Column out of bounds.")
+		fatal("Column out of bounds.")
 	}
 
 	if row > m.width || row < 1 {
-		log.Fatal("Row out of bounds.")
+		fatal("Row out of bounds.")
 	}
 	return m.data[col-1][row-1]
 }
 
-func (m *Matrix) GetRow(row int) []rune {
+func (m *Matrix) GetRow(row int) []Cell {
 	if row < 1 || row > m.height {
-		log.Fatal("Row out of bounds.")
+		fatal("Row out of bounds.")
 	}
 	return m.data[row-1]
 
 }
 
-func (m *Matrix) GetCol(col int) []rune {
+func (m *Matrix) GetCol(col int) []Cell {
 	if col < 1 || col > m.width {
-		log.Fatal("Column out of bounds.")
+		fatal("Column out of bounds.")
 	}
 
-	result := []rune{}
+	result := []Cell{}
 
 	for i := range m.data {
 		row := m.data[i]
@@ -78,9 +119,9 @@ func (m *Matrix) GrowV(n int) {
 
 	m.height += n
 	for i := 0; i < n; i++ {
-		row := make([]rune, m.width)
+		row := make([]Cell, m.width)
 		for i := range row {
-			row[i] = rune(' ')
+			row[i] = blankCell()
 		}
 
 		m.data = append(m.data, row)
@@ -98,7 +139,7 @@ func (m *Matrix) GrowH(n int) {
 		size := len(row)
 
 		for size < m.width {
-			row = append(row, rune(' '))
+			row = append(row, blankCell())
 			size = len(row)
 		}
 
@@ -106,8 +147,33 @@ func (m *Matrix) GrowH(n int) {
 	}
 }
 
+// Changes the size of the matrix, cropping or adding blank cells at the right
+// and bottom edges
+func (m *Matrix) Resize(width int, height int) {
+	if width < 1 || height < 1 {
+		fatal("Both width and height must be greater than 0.")
+	}
+
+	if width < m.width {
+		for i := range m.data {
+			m.data[i] = m.data[i][:width]
+		}
+		m.width = width
+	} else {
+		m.GrowH(width - m.width)
+	}
+
+	if height < m.height {
+		m.data = m.data[:height]
+		m.height = height
+	} else {
+		m.GrowV(height - m.height)
+	}
+}
+
 func (m *Matrix) Border(
 	depth int,
+	style Style,
 	t rune,
 	l rune,
 	b rune,
@@ -118,7 +184,7 @@ func (m *Matrix) Border(
 	br rune,
 ) {
 	if depth < 1 {
-		log.Fatal("Depth of a revolution cannot be less than 1.")
+		fatal("Depth of a revolution cannot be less than 1.")
 	}
 
 	top := depth
@@ -130,25 +196,25 @@ func (m *Matrix) Border(
 		for x := 1; x <= m.width; x++ {
 			if y == top {
 				if x == left {
-					m.Place(x, y, tl)
+					m.Place(x, y, NewCell(tl, style))
 				} else if x == right {
-					m.Place(x, y, tr)
+					m.Place(x, y, NewCell(tr, style))
 				} else {
-					m.Place(x, y, t)
+					m.Place(x, y, NewCell(t, style))
 				}
 			} else if y == bottom {
 				if x == left {
-					m.Place(x, y, bl)
+					m.Place(x, y, NewCell(bl, style))
 				} else if x == right {
-					m.Place(x, y, br)
+					m.Place(x, y, NewCell(br, style))
 				} else {
-					m.Place(x, y, b)
+					m.Place(x, y, NewCell(b, style))
 				}
 			} else {
 				if x == left {
-					m.Place(x, y, l)
+					m.Place(x, y, NewCell(l, style))
 				} else if x == right {
-					m.Place(x, y, r)
+					m.Place(x, y, NewCell(r, style))
 				}
 			}
 		}
@@ -157,11 +223,11 @@ func (m *Matrix) Border(
 
 func (m *Matrix) Slice(x int, y int, width int, height int) *Matrix {
 	if x+width > m.width || y+height > m.height || x < 0 || y < 0 {
-		log.Fatal("Slice out of bounds.")
+		fatal("Slice out of bounds.")
 	}
 
 	if width <= 0 || height <= 0 {
-		log.Fatal("Both width and height must be greater than 0.")
+		fatal("Both width and height must be greater than 0.")
 	}
 
 	matrix := NewMatrix(width, height)
@@ -180,7 +246,7 @@ func (m *Matrix) PlaceMatrix(x int, y int, matrix *Matrix) {
 	elementY := 0
 
 	matrix.ForEach(
-		func(colIndex int, rowIndex int, element rune, end bool) rune {
+		func(colIndex int, rowIndex int, element Cell, end bool) Cell {
 			elementX++
 			m.Place(x+elementX, y+elementY, element)
 
@@ -193,13 +259,37 @@ func (m *Matrix) PlaceMatrix(x int, y int, matrix *Matrix) {
 	)
 }
 
-func (m *Matrix) PlaceRow(x int, y int, row []rune) {
+// Copies a matrix into the area of this one, anything that falls outside of
+// the area or the matrix is clipped instead of growing it
+func (m *Matrix) Blit(rect Rect, matrix *Matrix) {
+	area := rect.Intersect(Rect{X: 1, Y: 1, Width: m.width, Height: m.height})
+
+	for y := area.Y; y < area.Y+area.Height; y++ {
+		sourceY := y - rect.Y + 1
+
+		if sourceY > matrix.height {
+			break
+		}
+
+		for x := area.X; x < area.X+area.Width; x++ {
+			sourceX := x - rect.X + 1
+
+			if sourceX > matrix.width {
+				break
+			}
+
+			m.data[y-1][x-1] = matrix.data[sourceY-1][sourceX-1]
+		}
+	}
+}
+
+func (m *Matrix) PlaceRow(x int, y int, row []Cell) {
 	if row == nil {
-		log.Fatal("Cannot place a row if nil.")
+		fatal("Cannot place a row if nil.")
 	}
 
 	if len(row) != m.width {
-		log.Fatal("Row have a different width than the matrix.")
+		fatal("Row have a different width than the matrix.")
 	}
 
 	for i, r := range row {
@@ -207,13 +297,13 @@ func (m *Matrix) PlaceRow(x int, y int, row []rune) {
 	}
 }
 
-func (m *Matrix) PlaceCol(x int, y int, col []rune) {
+func (m *Matrix) PlaceCol(x int, y int, col []Cell) {
 	if col == nil {
-		log.Fatal("Cannot place a column if nil.")
+		fatal("Cannot place a column if nil.")
 	}
 
 	if len(col) != m.height {
-		log.Fatal("Column have a different height than the matrix.")
+		fatal("Column have a different height than the matrix.")
 	}
 
 	for i, r := range col {
@@ -221,13 +311,13 @@ func (m *Matrix) PlaceCol(x int, y int, col []rune) {
 	}
 }
 
-func (m *Matrix) Place(x int, y int, element rune) {
+func (m *Matrix) Place(x int, y int, element Cell) {
 	if x < 0 {
-		log.Fatal("Cannot place an element at a negative X axis.")
+		fatal("Cannot place an element at a negative X axis.")
 	}
 
 	if y < 0 {
-		log.Fatal("Cannot place an element at a negative Y axis.")
+		fatal("Cannot place an element at a negative Y axis.")
 	}
 
 	if x > m.width {
@@ -246,6 +336,51 @@ func (m *Matrix) Place(x int, y int, element rune) {
 	m.data[newY][newX] = element
 }
 
+// Places a grapheme at the position, taking as many columns as it is wide,
+// and returns that width
+func (m *Matrix) PlaceGrapheme(x int, y int, text string, style Style) int {
+	g := splitGraphemes(text)
+
+	if len(g) == 0 {
+		return 0
+	}
+
+	m.Place(x, y, newGraphemeCell(g[0].text, style))
+
+	if g[0].width == 2 {
+		m.Place(x+1, y, continuationCell(style))
+	}
+
+	return g[0].width
+}
+
+// Places the text on a row starting at the position, returns the number of
+// columns taken
+func (m *Matrix) PlaceText(x int, y int, text string, style Style) int {
+	return m.placeGraphemes(x, y, splitGraphemes(text), style)
+}
+
+func (m *Matrix) placeGraphemes(x int, y int, graphemes []grapheme, style Style) int {
+	start := x
+
+	for _, g := range graphemes {
+		cellStyle := style
+		if g.style != nil {
+			cellStyle = g.style.inherit(style)
+		}
+
+		m.Place(x, y, newGraphemeCell(g.text, cellStyle))
+
+		if g.width == 2 {
+			m.Place(x+1, y, continuationCell(cellStyle))
+		}
+
+		x += g.width
+	}
+
+	return x - start
+}
+
 func (m *Matrix) ForEach(callback ElementCallback) {
 	if callback == nil {
 		return
@@ -265,10 +400,21 @@ func (m *Matrix) ForEach(callback ElementCallback) {
 }
 
 func (m *Matrix) ToBuffer() string {
+	return m.ToBufferFor(TrueColor)
+}
+
+// Converts the matrix into a string ready to be printed, with colors reduced
+// to what the terminal supports and SGR sequences emitted only where the style
+// changes between cells
+func (m *Matrix) ToBufferFor(support TerminalColor) string {
 	var builder strings.Builder
+	current := Style{}
 
-	m.ForEach(func(colIndex int, rowIndex int, element rune, end bool) rune {
-		builder.WriteRune(element)
+	m.ForEach(func(colIndex int, rowIndex int, element Cell, end bool) Cell {
+		style := element.Style.downsample(support)
+		builder.WriteString(styleTransition(current, style))
+		builder.WriteString(cellText(m.data[colIndex], rowIndex))
+		current = style
 
 		if end {
 			builder.WriteString("\n")
@@ -276,25 +422,26 @@ func (m *Matrix) ToBuffer() string {
 		return element
 	})
 
-	return builder.String()[:builder.Len()-1]
+	result := builder.String()[:builder.Len()-1]
+	return result + styleTransition(current, Style{})
 }
 
 func NewMatrix(width int, height int) *Matrix {
 	if width < 1 {
-		log.Fatal("Matrix width should be at least 1.")
+		fatal("Matrix width should be at least 1.")
 	}
 
 	if height < 1 {
-		log.Fatal("Matrix height should be at least 1.")
+		fatal("Matrix height should be at least 1.")
 	}
 
-	matrix := make([][]rune, height)
+	matrix := make([][]Cell, height)
 
 	for i := range matrix {
-		matrix[i] = make([]rune, width)
+		matrix[i] = make([]Cell, width)
 
 		for e := range matrix[i] {
-			matrix[i][e] = rune(' ')
+			matrix[i][e] = blankCell()
 		}
 	}
 
@@ -0,0 +1,113 @@
+package main
+
+type MouseMode int
+type MouseButton int
+type MouseAction int
+
+const (
+	MouseOff MouseMode = iota
+	// Reports presses, releases and the wheel
+	MouseClick
+	// Also reports motion while a button is held down
+	MouseButtonEvent
+	// Reports every motion, even with no button held down
+	MouseAnyMotion
+)
+
+const (
+	MouseNone MouseButton = iota
+	MouseLeft
+	MouseMiddle
+	MouseRight
+	MouseWheelUp
+	MouseWheelDown
+	MouseWheelLeft
+	MouseWheelRight
+	MouseBackward
+	MouseForward
+)
+
+const (
+	MousePress MouseAction = iota
+	MouseRelease
+	MouseDrag
+	MouseMotion
+	MouseWheel
+)
+
+// Decodes SGR (1006) mouse reports: ESC [ < button ; x ; y followed by M on
+// press and m on release
+func decodeSGRMouse(params []int, final byte) Event {
+	if len(params) < 3 {
+		return nil
+	}
+
+	code := params[0]
+	event := &OnMouse{
+		X:    params[1],
+		Y:    params[2],
+		Mods: mouseMods(code),
+	}
+
+	motion := code&32 != 0
+	base := code & 3
+
+	switch {
+	case code&64 != 0:
+		event.Action = MouseWheel
+		event.Button = []MouseButton{MouseWheelUp, MouseWheelDown, MouseWheelLeft, MouseWheelRight}[base]
+		return event
+	case code&128 != 0:
+		event.Button = []MouseButton{MouseBackward, MouseForward, MouseNone, MouseNone}[base]
+	default:
+		event.Button = []MouseButton{MouseLeft, MouseMiddle, MouseRight, MouseNone}[base]
+	}
+
+	switch {
+	case motion && event.Button == MouseNone:
+		event.Action = MouseMotion
+	case motion:
+		event.Action = MouseDrag
+	case final == 'm':
+		event.Action = MouseRelease
+	default:
+		event.Action = MousePress
+	}
+
+	return event
+}
+
+func mouseMods(code int) KeyMod {
+	mods := ModNone
+
+	if code&4 != 0 {
+		mods |= ModShift
+	}
+
+	if code&8 != 0 {
+		mods |= ModAlt
+	}
+
+	if code&16 != 0 {
+		mods |= ModCtrl
+	}
+
+	return mods
+}
+
+type componentRegion struct {
+	component Component
+	rect      Rect
+}
+
+// Returns the component drawn last at the given cell, which is the innermost
+// and topmost one
+func hitTest(regions []componentRegion, x int, y int) Component {
+	for i := len(regions) - 1; i >= 0; i-- {
+		if regions[i].rect.Contains(x, y) {
+			return regions[i].component
+		}
+	}
+
+	return nil
+}
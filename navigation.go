@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+type ResumeReason int
+
+const (
+	// The screen above was popped
+	ResumeFromPop ResumeReason = iota
+	// The process was continued after being suspended with Ctrl+Z
+	ResumeFromSuspend
+)
+
+type screenEntry struct {
+	screen Screen
+
+	// Cancelled when the screen is destroyed, stopping its commands
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	timers []*Timer
+	mutex  sync.Mutex
+}
+
+// Keeps the timer so it is stopped along with the screen, returns false if
+// the screen is already destroyed
+func (e *screenEntry) track(timer *Timer) bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.ctx.Err() != nil {
+		return false
+	}
+
+	running := []*Timer{timer}
+
+	for _, t := range e.timers {
+		if !t.Stopped() {
+			running = append(running, t)
+		}
+	}
+
+	e.timers = running
+	return true
+}
+
+func (e *screenEntry) destroy() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.cancel()
+
+	for _, timer := range e.timers {
+		timer.Stop()
+	}
+
+	e.timers = nil
+}
+
+func newScreenEntry(screen Screen) *screenEntry {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &screenEntry{screen: screen, ctx: ctx, cancel: cancel}
+}
+
+func (r *Renderer) activeScreen() Screen {
+	return r.screens.Peek().screen
+}
+
+// Delivers the event to the screen of the entry and runs the command it
+// returns on behalf of that screen
+func (r *Renderer) sendEvent(entry *screenEntry, event Event) {
+	r.context.setOwner(entry)
+	r.runCommand(entry, entry.screen.OnEvent(r.context, event))
+}
+
+func (r *Renderer) update(entry *screenEntry) {
+	r.context.setOwner(entry)
+	r.runCommand(entry, entry.screen.Update(r.context))
+}
+
+func (r *Renderer) pushScreen(screen Screen) {
+	if screen == nil {
+		return
+	}
+
+	r.sendEvent(r.screens.Peek(), &OnPause{})
+
+	entry := newScreenEntry(screen)
+	r.screens.Push(entry)
+	r.sendEvent(entry, &OnCreate{})
+	r.context.RequestRefresh()
+}
+
+// Destroys the active screen and resumes the one below with the result. The
+// application exits when the last screen is popped.
+func (r *Renderer) popScreen(result any) {
+	if r.screens.Size() <= 1 {
+		r.handleSignal(SigExit)
+		return
+	}
+
+	popped := r.screens.Pop()
+	r.sendEvent(popped, &OnDestroy{})
+	popped.destroy()
+
+	r.sendEvent(r.screens.Peek(), &OnResume{Reason: ResumeFromPop, Result: result})
+	r.context.RequestRefresh()
+}
+
+func (r *Renderer) replaceScreen(screen Screen) {
+	if screen == nil {
+		return
+	}
+
+	replaced := r.screens.Pop()
+	r.sendEvent(replaced, &OnDestroy{})
+	replaced.destroy()
+
+	entry := newScreenEntry(screen)
+	r.screens.Push(entry)
+	r.sendEvent(entry, &OnCreate{})
+	r.context.RequestRefresh()
+}
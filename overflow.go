@@ -0,0 +1,187 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Columns dimmed at the end of the last line by OverflowFade
+const fadeWidth = 3
+
+type textOverflow struct {
+	mode   Overflow
+	marker string
+}
+
+// Cuts the lines down to the maximum, marking where the text was hidden
+func (o textOverflow) truncate(lines []textLine, width int, maxLines int) []textLine {
+	if maxLines <= 0 || len(lines) <= maxLines {
+		return lines
+	}
+
+	hidden := len(lines) - maxLines
+	result := []textLine{}
+
+	switch o.mode {
+	case OverflowEnd:
+		result = append(result, lines[:maxLines]...)
+		last := result[maxLines-1].graphemes
+		result[maxLines-1] = textLine{graphemes: o.truncateEnd(last, width, hidden), last: true}
+	case OverflowStart:
+		// The first line stands for everything that was hidden, so it is
+		// filled with as much of its end as fits
+		first := textLine{graphemes: o.truncateStart(joinLines(lines[:hidden+1]), width, hidden), last: lines[hidden].last}
+		result = append(result, first)
+		result = append(result, lines[hidden+1:]...)
+	case OverflowMiddle:
+		// The line in the middle stands for everything that was hidden, it
+		// takes the start of the first hidden line and the end of the last
+		before := (maxLines - 1) / 2
+		after := maxLines - 1 - before
+		joined := joinLines(lines[before : len(lines)-after])
+
+		result = append(result, lines[:before]...)
+		result = append(result, textLine{graphemes: o.truncateMiddle(joined, width, hidden), last: true})
+		result = append(result, lines[len(lines)-after:]...)
+	case OverflowFade:
+		result = append(result, lines[:maxLines]...)
+		last := result[maxLines-1].graphemes
+		result[maxLines-1] = textLine{graphemes: fade(last), last: true}
+	default:
+		result = append(result, lines[:maxLines]...)
+	}
+
+	return result
+}
+
+func (o textOverflow) truncateEnd(line []grapheme, width int, hidden int) []grapheme {
+	line = trimTrailingSpaces(line)
+	marker := o.markerGraphemes(width, hidden)
+	available := width - graphemesWidth(marker)
+
+	for len(line) > 0 && graphemesWidth(line) > available {
+		line = line[:len(line)-1]
+	}
+
+	result := append([]grapheme{}, line...)
+	return append(result, styleGraphemes(marker, lastStyle(line))...)
+}
+
+func (o textOverflow) truncateStart(line []grapheme, width int, hidden int) []grapheme {
+	line = trimLeadingSpaces(trimTrailingSpaces(line))
+	marker := o.markerGraphemes(width, hidden)
+	available := width - graphemesWidth(marker)
+
+	for len(line) > 0 && graphemesWidth(line) > available {
+		line = line[1:]
+	}
+
+	var style *Style
+	if len(line) > 0 {
+		style = line[0].style
+	}
+
+	result := styleGraphemes(marker, style)
+	return append(result, line...)
+}
+
+func (o textOverflow) truncateMiddle(line []grapheme, width int, hidden int) []grapheme {
+	line = trimLeadingSpaces(trimTrailingSpaces(line))
+	marker := o.markerGraphemes(width, hidden)
+	available := width - graphemesWidth(marker)
+
+	// The start gets the extra column when the space is odd
+	headWidth := (available + 1) / 2
+	tailWidth := available - headWidth
+
+	head := []grapheme{}
+	for _, g := range line {
+		if graphemesWidth(head)+g.width > headWidth {
+			break
+		}
+		head = append(head, g)
+	}
+
+	tail := []grapheme{}
+	for i := len(line) - 1; i >= len(head); i-- {
+		if graphemesWidth(tail)+line[i].width > tailWidth {
+			break
+		}
+		tail = append([]grapheme{line[i]}, tail...)
+	}
+
+	result := append([]grapheme{}, head...)
+	result = append(result, styleGraphemes(marker, lastStyle(head))...)
+	return append(result, tail...)
+}
+
+// The marker with the number of hidden lines filled in, cut to the width if
+// it does not fit
+func (o textOverflow) markerGraphemes(width int, hidden int) []grapheme {
+	marker := strings.ReplaceAll(o.marker, "%d", strconv.Itoa(hidden))
+
+	graphemes := splitGraphemes(marker)
+
+	for len(graphemes) > 0 && graphemesWidth(graphemes) > width {
+		graphemes = graphemes[:len(graphemes)-1]
+	}
+
+	return graphemes
+}
+
+// Dims the last columns of the line, the ones that would come before the
+// hidden text
+func fade(line []grapheme) []grapheme {
+	result := append([]grapheme{}, trimTrailingSpaces(line)...)
+	faded := 0
+
+	for i := len(result) - 1; i >= 0 && faded < fadeWidth; i-- {
+		style := Style{attrs: AttrDim}
+
+		if result[i].style != nil {
+			style = style.inherit(*result[i].style)
+		}
+
+		result[i].style = &style
+		faded += result[i].width
+	}
+
+	return result
+}
+
+// Gives the marker the style of the text next to it
+func styleGraphemes(graphemes []grapheme, style *Style) []grapheme {
+	result := make([]grapheme, len(graphemes))
+
+	for i, g := range graphemes {
+		g.style = style
+		result[i] = g
+	}
+
+	return result
+}
+
+func joinLines(lines []textLine) []grapheme {
+	joined := []grapheme{}
+
+	for _, line := range lines {
+		joined = append(joined, line.graphemes...)
+	}
+
+	return joined
+}
+
+func lastStyle(line []grapheme) *Style {
+	if len(line) == 0 {
+		return nil
+	}
+	return line[len(line)-1].style
+}
+
+func trimLeadingSpaces(line []grapheme) []grapheme {
+	for len(line) > 0 && line[0].isSpace() {
+		line = line[1:]
+	}
+
+	return line
+}
@@ -1,9 +1,5 @@
 package main
 
-type QueueData interface {
-	string | Signal
-}
-
 type QueueError struct {
 	message string
 }
@@ -12,7 +8,7 @@ func (e *QueueError) Error() string {
 	return e.message
 }
 
-type Queue[T QueueData] struct {
+type Queue[T any] struct {
 	values []T
 }
 
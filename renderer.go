@@ -1,15 +1,43 @@
 package main
 
 import (
-	"fmt"
-	"log"
 	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
 )
 
+// Longest run of unchanged cells rewritten to avoid a cursor movement, which
+// takes around as many bytes
+const maxDiffGap = 6
+
+// Frames drawn per second at most, unless changed with SetFrameRate
+const defaultFrameRate = 60
+
 type Renderer struct {
 	terminal *Terminal
 	context  *Context
 	canva    *Matrix
+	front    *Matrix
+	input    *InputReader
+	screens  Stack[*screenEntry]
+	resize   chan os.Signal
+	resume   chan os.Signal
+	commands chan commandResult
+
+	// Handlers for the signals sent through the context, keyed by their type
+	signalHandlers map[reflect.Type]SignalHandler
+
+	// Forces the next frame to be written in full instead of diffed
+	fullRepaint bool
+
+	// Frame rate limiting, the timer is set while a frame is waiting
+	lastFrame  time.Time
+	frameTimer ClockTimer
+	frameDue   chan struct{}
 
 	// Viewport
 	offsetX int
@@ -22,39 +50,350 @@ func (r *Renderer) OpenScreen(screen Screen) {
 	r.checkContext()
 
 	if screen == nil {
-		log.Fatal("Cannot open screen: screen is nil.")
+		fatal("Cannot open screen: screen is nil.")
 	}
 
-	screen.OnEvent(r.context, &OnWindowCreate{})
-	screen.OnEvent(r.context, &OnCreate{})
+	defer r.terminal.RecoverPanic()
+
+	root := newScreenEntry(screen)
+	r.screens.Push(root)
+	r.sendEvent(root, &OnWindowCreate{})
+	r.sendEvent(root, &OnCreate{})
 
-	// Main loop
+	r.input.Start()
+	signal.Notify(r.resize, unix.SIGWINCH)
+
+	if r.terminal.HasJobControl() {
+		signal.Notify(r.resume, unix.SIGCONT)
+	}
+
+	// Set to nil once the input closes, so the loop stops selecting on it
+	input := r.input.Events()
+
+	// Main loop, blocks until there is something to do so the process stays
+	// idle between events
 	for {
-		if r.context.refresh {
-			m, x, y := screen.View(r.context).Render()
-			r.canva.PlaceMatrix(x, y, m)
-			fmt.Print(r.canva.ToBuffer())
-			r.context.refresh = false
+		for {
+			sig, ok := r.context.nextSignal()
+			if !ok {
+				break
+			}
+			r.handleSignal(sig)
 		}
 
-		for !(r.context.signals.IsEmpty()) {
-			r.handleSignal(r.context.signals.Dequeue())
+		entry := r.screens.Peek()
+
+		r.drawFrame(entry.screen)
+
+		select {
+		case event, ok := <-input:
+			if !ok {
+				r.handleSignal(SigExit)
+				input = nil
+				continue
+			}
+
+			if mouse, ok := event.(*OnMouse); ok {
+				mouse.Target = r.canva.ComponentAt(mouse.X, mouse.Y)
+			}
+
+			if r.isSuspendKey(event) {
+				r.sendEvent(entry, &OnSuspend{})
+				r.terminal.Suspend()
+				continue
+			}
+
+			r.sendEvent(entry, event)
+			r.context.RequestRefresh()
+		case posted := <-r.context.events:
+			r.handlePosted(entry, posted)
+		case result := <-r.commands:
+			r.handleCommandResult(result)
+		case <-r.resize:
+			r.handleResize(entry)
+		case <-r.resume:
+			r.handleResume(entry)
+		case <-r.frameDue:
+			r.frameTimer = nil
+		case <-r.context.wake:
+			// The frame is drawn once it is due, until then there is nothing
+			// to update
+			if r.frameTimer != nil {
+				continue
+			}
 		}
-		screen.Update(r.context)
+
+		// Signals sent while handling the event may have changed the screen
+		entry = r.screens.Peek()
+		r.update(entry)
+	}
+}
+
+// Events posted by timers go to the screen that started them, as long as it
+// has not been destroyed
+func (r *Renderer) handlePosted(active *screenEntry, posted screenEvent) {
+	if posted.done != nil {
+		posted.done()
+	}
+
+	entry := posted.entry
+
+	if entry == nil {
+		entry = active
+	}
+
+	if entry.ctx.Err() != nil {
+		return
 	}
+
+	r.sendEvent(entry, posted.event)
+
+	if entry == active {
+		r.context.RequestRefresh()
+	}
+}
+
+// Draws the pending refresh, unless the last frame is too recent for the frame
+// rate, in which case the loop is woken up once the frame is due. Refreshes
+// requested in the meantime are drawn together.
+func (r *Renderer) drawFrame(screen Screen) {
+	if r.frameTimer != nil || !r.context.hasRefresh() {
+		return
+	}
+
+	clock, interval := r.frameClock()
+	wait := interval - clock.Now().Sub(r.lastFrame)
+
+	if wait > 0 {
+		r.frameTimer = clock.AfterFunc(wait, func() {
+			select {
+			case r.frameDue <- struct{}{}:
+			default:
+			}
+		})
+		return
+	}
+
+	r.render(screen)
+}
+
+func (r *Renderer) render(screen Screen) {
+	// Taken before the view is built, so requests made while building it are
+	// kept for the next frame
+	r.context.clearRefresh()
+
+	clock, _ := r.frameClock()
+	r.lastFrame = clock.Now()
+
+	view := screen.View(r.context)
+	window := Constraints{MaxWidth: r.width, MaxHeight: r.height}
+	width, height := view.Measure(window)
+	x, y := locate(view, r.width, r.height, width, height)
+
+	r.canva.Clear()
+	r.canva.TrackRegions()
+	arrangeChild(r.canva, view, Rect{X: x, Y: y, Width: width, Height: height})
+	r.flush()
+}
+
+func (r *Renderer) frameClock() (Clock, time.Duration) {
+	r.context.mutex.Lock()
+	defer r.context.mutex.Unlock()
+
+	return r.context.clock, r.context.frameInterval
+}
+
+// Limits how many frames are drawn per second, 0 or less removes the limit.
+// Frame ticks started afterwards follow the same rate.
+func (r *Renderer) SetFrameRate(fps int) {
+	interval := time.Duration(0)
+
+	if fps > 0 {
+		interval = time.Second / time.Duration(fps)
+	}
+
+	r.context.mutex.Lock()
+	r.context.frameInterval = interval
+	r.context.mutex.Unlock()
+}
+
+// Replaces the clock used by the timers of the context, timers that are
+// already running keep the previous one
+func (r *Renderer) SetClock(clock Clock) {
+	r.context.mutex.Lock()
+	r.context.clock = clock
+	r.context.mutex.Unlock()
+}
+
+// Forces the next frame to repaint the whole screen
+func (r *Renderer) Invalidate() {
+	r.fullRepaint = true
+	r.context.RequestRefresh()
+}
+
+// Writes the back buffer to the terminal, only sending the cells that changed
+// since the last flushed frame
+func (r *Renderer) flush() {
+	var builder strings.Builder
+
+	if r.fullRepaint || r.front == nil ||
+		r.front.Width() != r.canva.Width() || r.front.Height() != r.canva.Height() {
+		builder.WriteString(escMoveCursorTop)
+		builder.WriteString(r.canva.ToBufferFor(r.terminal.GetColorSupport()))
+		r.fullRepaint = false
+	} else {
+		r.writeDiff(&builder)
+	}
+
+	r.front = r.canva.Clone()
+	os.Stdout.WriteString(builder.String())
+}
+
+func (r *Renderer) writeDiff(builder *strings.Builder) {
+	width := min(r.canva.Width(), r.width)
+	height := min(r.canva.Height(), r.height)
+	current := Style{}
+	support := r.terminal.GetColorSupport()
+
+	for y := 1; y <= height; y++ {
+		back := r.canva.GetRow(y)
+		front := r.front.GetRow(y)
+
+		x := 1
+		for x <= width {
+			if back[x-1] == front[x-1] {
+				x++
+				continue
+			}
+
+			// Wide characters are written whole, starting from their first
+			// column
+			if back[x-1].Continuation && x > 1 {
+				x--
+			}
+
+			// Extends the run over short stretches of unchanged cells, since
+			// rewriting them is cheaper than moving the cursor again
+			end := x
+			gap := 0
+			for i := x + 1; i <= width && gap <= maxDiffGap; i++ {
+				if back[i-1] != front[i-1] {
+					end = i
+					gap = 0
+				} else {
+					gap++
+				}
+			}
+
+			if end < width && back[end].Continuation {
+				end++
+			}
+
+			builder.WriteString(escMoveCursor(x, y))
+			for i := x; i <= end; i++ {
+				style := back[i-1].Style.downsample(support)
+				builder.WriteString(styleTransition(current, style))
+				builder.WriteString(cellText(back, i-1))
+				current = style
+			}
+
+			x = end + 1
+		}
+	}
+
+	builder.WriteString(styleTransition(current, Style{}))
+}
+
+func (r *Renderer) handleResize(entry *screenEntry) {
+	w, h := r.terminal.GetTerminalSize()
+
+	if w == r.width && h == r.height {
+		return
+	}
+
+	event := &OnResize{
+		OldWidth:  r.width,
+		OldHeight: r.height,
+		Width:     w,
+		Height:    h,
+	}
+
+	r.width = w
+	r.height = h
+	r.canva = NewMatrix(w, h)
+	r.context.window.Width = w
+	r.context.window.Height = h
+
+	r.sendEvent(entry, event)
+
+	// The old contents are wrapped or cut by the terminal, so everything
+	// is painted again from a blank screen
+	r.terminal.ClearAlternateBuffer()
+	r.fullRepaint = true
+	r.context.RequestRefresh()
+}
+
+func (r *Renderer) isSuspendKey(event Event) bool {
+	key, ok := event.(*OnKeyPress)
+
+	return ok && r.terminal.HasJobControl() &&
+		key.Key == KeyRune && key.Rune == 'z' && key.Mods == ModCtrl
+}
+
+// Called once the process is continued after being suspended, the terminal
+// may have been resized or drawn over in the meantime
+func (r *Renderer) handleResume(entry *screenEntry) {
+	r.terminal.Resume()
+	r.handleResize(entry)
+
+	r.sendEvent(entry, &OnResume{Reason: ResumeFromSuspend})
+
+	r.fullRepaint = true
+	r.context.RequestRefresh()
+}
+
+// Registers the handler for every signal of the same type as the given one,
+// replacing the previous handler, including the built-in ones
+func (r *Renderer) HandleSignal(signal Signal, handler SignalHandler) {
+	r.signalHandlers[signalType(signal)] = handler
 }
 
 func (r *Renderer) handleSignal(signal Signal) {
-	switch signal {
-	case SigExit:
+	if handler, ok := r.signalHandlers[signalType(signal)]; ok {
+		handler(r.context, signal)
+		return
+	}
+
+	r.sendEvent(r.screens.Peek(), &OnSignal{Signal: signal})
+}
+
+func (r *Renderer) registerBuiltinSignals() {
+	r.HandleSignal(&ExitSignal{}, func(ctx *Context, signal Signal) {
 		r.terminal.Restore()
 		os.Exit(0)
-	}
+	})
+
+	r.HandleSignal(&RefreshSignal{}, func(ctx *Context, signal Signal) {
+		r.fullRepaint = true
+		r.render(r.activeScreen())
+	})
+
+	r.HandleSignal(&PushScreenSignal{}, func(ctx *Context, signal Signal) {
+		r.pushScreen(signal.(*PushScreenSignal).Screen)
+	})
+
+	r.HandleSignal(&PopScreenSignal{}, func(ctx *Context, signal Signal) {
+		r.popScreen(signal.(*PopScreenSignal).Result)
+	})
+
+	r.HandleSignal(&ReplaceScreenSignal{}, func(ctx *Context, signal Signal) {
+		r.replaceScreen(signal.(*ReplaceScreenSignal).Screen)
+	})
 }
 
 func (r *Renderer) checkContext() {
 	if r.context == nil {
-		log.Fatal("Cannot initialize renderer if context is nil.")
+		fatal("Cannot initialize renderer if context is nil.")
 	}
 }
 
@@ -63,13 +402,24 @@ func NewRenderer(term *Terminal) *Renderer {
 	ctx := NewContext(w, h)
 	canva := NewMatrix(w, h)
 
-	return &Renderer{
+	renderer := &Renderer{
 		terminal: term,
 		context:  ctx,
 		canva:    canva,
+		input:    NewInputReader(os.Stdin),
+		resize:   make(chan os.Signal, 1),
+		resume:   make(chan os.Signal, 1),
+		commands: make(chan commandResult),
+		frameDue: make(chan struct{}, 1),
 		offsetX:  0,
 		offsetY:  0,
 		width:    w,
 		height:   h,
+
+		signalHandlers: map[reflect.Type]SignalHandler{},
 	}
+
+	renderer.registerBuiltinSignals()
+
+	return renderer
 }
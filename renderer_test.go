@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// Dashboard-like frame, a styled header, a body of text and a status line
+func benchmarkFrame(width int, height int, tick int) *Matrix {
+	matrix := NewMatrix(width, height)
+	header := Style{fg: ColorBlack, bg: ColorCyan, attrs: AttrBold}
+	body := Style{fg: ColorWhite}
+	status := Style{fg: ColorYellow, bg: ColorBlue}
+
+	matrix.PlaceText(1, 1, fmt.Sprintf("%-*s", width, " Dashboard"), header)
+
+	for y := 3; y < height-1; y++ {
+		matrix.PlaceText(3, y, fmt.Sprintf("row %02d %s", y, strings.Repeat("data ", 10)), body)
+	}
+
+	// Only the spinner, a counter and the status line change between frames
+	spinner := []string{"|", "/", "-", "\\"}[tick%4]
+	matrix.PlaceText(width-2, 1, spinner, header)
+	matrix.PlaceText(60, 10, fmt.Sprintf("%6d", tick*37), body)
+	matrix.PlaceText(1, height, fmt.Sprintf("%-*s", width, fmt.Sprintf(" frame %d", tick)), status)
+
+	return matrix
+}
+
+func BenchmarkWriteDiff(b *testing.B) {
+	const width, height = 120, 40
+
+	renderer := &Renderer{
+		terminal: &Terminal{colorSupport: TrueColor},
+		front:    benchmarkFrame(width, height, 0),
+		canva:    benchmarkFrame(width, height, 1),
+		width:    width,
+		height:   height,
+	}
+
+	full := len(renderer.canva.ToBufferFor(TrueColor))
+	diff := 0
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var builder strings.Builder
+		renderer.writeDiff(&builder)
+		diff = builder.Len()
+	}
+
+	b.ReportMetric(float64(diff), "diff-bytes/frame")
+	b.ReportMetric(float64(full), "full-bytes/frame")
+}
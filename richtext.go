@@ -0,0 +1,192 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Piece of text drawn with its own style. Colors left as default and missing
+// attributes are taken from the style of the component.
+type Span struct {
+	Text  string
+	Style *Style
+}
+
+func NewSpan(text string, style *Style) Span {
+	return Span{Text: text, Style: style}
+}
+
+// Parses text with inline styling tags into spans. A tag lists attributes and
+// colors separated by spaces, with "on" before the background color, and
+// applies until it is closed with [/]:
+//
+//	[bold red]error[/] in [italic #ff8800 on black]main.go[/]
+//
+// Tags can be nested. Attributes are bold, dim, italic, underline, reverse and
+// strike, colors are the ANSI names such as red or bright_red, palette indexes
+// written as color(0) to color(255) and hex codes. Anything that is not a
+// valid tag is kept as text, so brackets like [1] stay as they are, and [[
+// writes a literal bracket.
+func Markup(markup string) []Span {
+	spans := []Span{}
+	stack := []*Style{}
+	var builder strings.Builder
+
+	current := func() *Style {
+		if len(stack) == 0 {
+			return nil
+		}
+		return stack[len(stack)-1]
+	}
+
+	flush := func() {
+		if builder.Len() > 0 {
+			spans = append(spans, Span{Text: builder.String(), Style: current()})
+			builder.Reset()
+		}
+	}
+
+	for len(markup) > 0 {
+		if strings.HasPrefix(markup, "[[") {
+			builder.WriteByte('[')
+			markup = markup[2:]
+			continue
+		}
+
+		end := strings.IndexByte(markup, ']')
+
+		if markup[0] != '[' || end == -1 {
+			builder.WriteByte(markup[0])
+			markup = markup[1:]
+			continue
+		}
+
+		tag := markup[1:end]
+
+		if strings.HasPrefix(tag, "/") {
+			flush()
+
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+
+			markup = markup[end+1:]
+			continue
+		}
+
+		style, ok := parseStyleTag(tag)
+
+		if !ok {
+			builder.WriteByte(markup[0])
+			markup = markup[1:]
+			continue
+		}
+
+		flush()
+
+		if parent := current(); parent != nil {
+			style = style.inherit(*parent)
+		}
+
+		stack = append(stack, &style)
+		markup = markup[end+1:]
+	}
+
+	flush()
+	return spans
+}
+
+func parseStyleTag(tag string) (Style, bool) {
+	style := Style{}
+	words := strings.Fields(tag)
+
+	if len(words) == 0 {
+		return style, false
+	}
+
+	for i := 0; i < len(words); i++ {
+		word := strings.ToLower(words[i])
+
+		if attr, ok := markupAttributes[word]; ok {
+			style.attrs |= attr
+			continue
+		}
+
+		if word == "on" && i+1 < len(words) {
+			color, ok := parseColorName(words[i+1])
+
+			if !ok {
+				return style, false
+			}
+
+			style.bg = color
+			i++
+			continue
+		}
+
+		color, ok := parseColorName(word)
+
+		if !ok {
+			return style, false
+		}
+
+		style.fg = color
+	}
+
+	return style, true
+}
+
+var markupAttributes = map[string]Attribute{
+	"bold":          AttrBold,
+	"dim":           AttrDim,
+	"italic":        AttrItalic,
+	"underline":     AttrUnderline,
+	"reverse":       AttrReverse,
+	"strike":        AttrStrikethrough,
+	"strikethrough": AttrStrikethrough,
+}
+
+var markupColors = map[string]Color{
+	"default":        ColorDefault,
+	"black":          ColorBlack,
+	"red":            ColorRed,
+	"green":          ColorGreen,
+	"yellow":         ColorYellow,
+	"blue":           ColorBlue,
+	"magenta":        ColorMagenta,
+	"cyan":           ColorCyan,
+	"white":          ColorWhite,
+	"bright_black":   ColorBrightBlack,
+	"bright_red":     ColorBrightRed,
+	"bright_green":   ColorBrightGreen,
+	"bright_yellow":  ColorBrightYellow,
+	"bright_blue":    ColorBrightBlue,
+	"bright_magenta": ColorBrightMagenta,
+	"bright_cyan":    ColorBrightCyan,
+	"bright_white":   ColorBrightWhite,
+}
+
+func parseColorName(name string) (Color, bool) {
+	name = strings.ToLower(name)
+
+	if color, ok := markupColors[name]; ok {
+		return color, true
+	}
+
+	// Bare numbers are common in text, so indexes need the explicit form
+	if index, ok := strings.CutPrefix(name, "color("); ok {
+		if index, ok := strings.CutSuffix(index, ")"); ok {
+			if index, err := strconv.ParseUint(index, 10, 8); err == nil {
+				return ColorIndex(uint8(index)), true
+			}
+		}
+	}
+
+	if strings.HasPrefix(name, "#") && (len(name) == 4 || len(name) == 7) {
+		if _, err := strconv.ParseUint(name[1:], 16, 32); err == nil {
+			return ColorHex(name), true
+		}
+	}
+
+	return ColorDefault, false
+}
@@ -1,7 +1,9 @@
 package main
 
+// Both OnEvent and Update can return a command to run work in the background,
+// or nil when there is nothing to do
 type Screen interface {
-	OnEvent(ctx *Context, event Event)
-	Update(ctx *Context)
+	OnEvent(ctx *Context, event Event) Cmd
+	Update(ctx *Context) Cmd
 	View(ctx *Context) Component
 }
@@ -1,8 +1,77 @@
 package main
 
-type Signal int
+import "reflect"
 
-const (
-	SigNone Signal = iota
-	SigExit
-)
+// Signals are requests sent to the renderer through Context.SendSignal. The
+// renderer handles them with the handler registered for their type, and those
+// without a handler are delivered to the active screen as an OnSignal event,
+// so applications can define their own.
+type Signal interface {
+	Payload() map[string]any
+}
+
+type SignalHandler func(ctx *Context, signal Signal)
+
+type ExitSignal struct {
+}
+
+func (s *ExitSignal) Payload() map[string]any {
+	return nil
+}
+
+// Draws the screen again right away, in full and ignoring the frame rate
+type RefreshSignal struct {
+}
+
+func (s *RefreshSignal) Payload() map[string]any {
+	return nil
+}
+
+type PushScreenSignal struct {
+	Screen Screen
+}
+
+func (s *PushScreenSignal) Payload() map[string]any {
+	return map[string]any{
+		"screen": s.Screen,
+	}
+}
+
+type PopScreenSignal struct {
+	Result any
+}
+
+func (s *PopScreenSignal) Payload() map[string]any {
+	return map[string]any{
+		"result": s.Result,
+	}
+}
+
+type ReplaceScreenSignal struct {
+	Screen Screen
+}
+
+func (s *ReplaceScreenSignal) Payload() map[string]any {
+	return map[string]any{
+		"screen": s.Screen,
+	}
+}
+
+var SigExit Signal = &ExitSignal{}
+var SigRefresh Signal = &RefreshSignal{}
+
+func SigPushScreen(screen Screen) Signal {
+	return &PushScreenSignal{Screen: screen}
+}
+
+func SigPopScreen(result any) Signal {
+	return &PopScreenSignal{Result: result}
+}
+
+func SigReplaceScreen(screen Screen) Signal {
+	return &ReplaceScreenSignal{Screen: screen}
+}
+
+func signalType(signal Signal) reflect.Type {
+	return reflect.TypeOf(signal)
+}
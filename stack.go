@@ -1,9 +1,5 @@
 package main
 
-type StackData interface {
-	string | Signal
-}
-
 type StackError struct {
 	message string
 }
@@ -12,7 +8,7 @@ func (e *StackError) Error() string {
 	return e.message
 }
 
-type Stack[T StackData] struct {
+type Stack[T any] struct {
 	values []T
 }
 
@@ -0,0 +1,188 @@
+package main
+
+import "strings"
+
+type Attribute int
+
+const (
+	AttrNone Attribute = 0
+	AttrBold Attribute = 1 << (iota - 1)
+	AttrDim
+	AttrItalic
+	AttrUnderline
+	AttrReverse
+	AttrStrikethrough
+)
+
+type Style struct {
+	fg    Color
+	bg    Color
+	attrs Attribute
+}
+
+func (s Style) Fg() Color {
+	return s.fg
+}
+
+func (s Style) Bg() Color {
+	return s.bg
+}
+
+func (s Style) Attrs() Attribute {
+	return s.attrs
+}
+
+func (s Style) Has(attr Attribute) bool {
+	return s.attrs&attr != 0
+}
+
+func (s *Style) Eval() Style {
+	return *s
+}
+
+// Fills what the style leaves unset with the parent, default colors are
+// taken from it and attributes are added to its own
+func (s Style) inherit(parent Style) Style {
+	if s.fg.IsDefault() {
+		s.fg = parent.fg
+	}
+
+	if s.bg.IsDefault() {
+		s.bg = parent.bg
+	}
+
+	s.attrs |= parent.attrs
+	return s
+}
+
+func (s Style) downsample(support TerminalColor) Style {
+	s.fg = s.fg.downsample(support)
+	s.bg = s.bg.downsample(support)
+	return s
+}
+
+func NewStyle(fg Color, bg Color, attrs Attribute) *Style {
+	return &Style{fg: fg, bg: bg, attrs: attrs}
+}
+
+func NewFg(fg Color) *Style {
+	return &Style{fg: fg, bg: ColorDefault}
+}
+
+type Cell struct {
+	Rune  rune
+	Style Style
+
+	// Rest of the grapheme when it takes more than one rune, like combining
+	// marks or emoji sequences
+	Extra string
+
+	// Set on the cell covered by the second column of a wide character
+	Continuation bool
+}
+
+func (c Cell) String() string {
+	if c.Continuation {
+		return ""
+	}
+
+	return string(c.Rune) + c.Extra
+}
+
+func NewCell(r rune, style Style) Cell {
+	return Cell{Rune: r, Style: style}
+}
+
+func newGraphemeCell(text string, style Style) Cell {
+	r, extra := firstRune(text)
+	return Cell{Rune: r, Style: style, Extra: extra}
+}
+
+func continuationCell(style Style) Cell {
+	return Cell{Style: style, Continuation: true}
+}
+
+func blankCell() Cell {
+	return Cell{Rune: rune(' ')}
+}
+
+// Returns the shortest SGR sequence that switches the terminal from one style
+// to the other, or an empty string if they are the same
+func styleTransition(from Style, to Style) string {
+	if from == to {
+		return ""
+	}
+
+	if to == (Style{}) {
+		return "\033[0m"
+	}
+
+	params := []string{}
+	removed := from.attrs &^ to.attrs
+	added := to.attrs &^ from.attrs
+
+	// Bold and dim share the same reset code, so whichever one is kept has to
+	// be turned on again
+	if removed&(AttrBold|AttrDim) != 0 {
+		params = append(params, "22")
+		added |= to.attrs & (AttrBold | AttrDim)
+	}
+
+	if removed&AttrItalic != 0 {
+		params = append(params, "23")
+	}
+
+	if removed&AttrUnderline != 0 {
+		params = append(params, "24")
+	}
+
+	if removed&AttrReverse != 0 {
+		params = append(params, "27")
+	}
+
+	if removed&AttrStrikethrough != 0 {
+		params = append(params, "29")
+	}
+
+	params = append(params, attributeParams(added)...)
+
+	if from.fg != to.fg {
+		params = append(params, to.fg.sgr(false))
+	}
+
+	if from.bg != to.bg {
+		params = append(params, to.bg.sgr(true))
+	}
+
+	return "\033[" + strings.Join(params, ";") + "m"
+}
+
+func attributeParams(attrs Attribute) []string {
+	params := []string{}
+
+	if attrs&AttrBold != 0 {
+		params = append(params, "1")
+	}
+
+	if attrs&AttrDim != 0 {
+		params = append(params, "2")
+	}
+
+	if attrs&AttrItalic != 0 {
+		params = append(params, "3")
+	}
+
+	if attrs&AttrUnderline != 0 {
+		params = append(params, "4")
+	}
+
+	if attrs&AttrReverse != 0 {
+		params = append(params, "7")
+	}
+
+	if attrs&AttrStrikethrough != 0 {
+		params = append(params, "9")
+	}
+
+	return params
+}
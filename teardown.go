@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"runtime/debug"
+	"sync/atomic"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Terminal that has to be restored before the program exits on an error,
+// nil until one is initialised
+var fatalTerminal atomic.Pointer[Terminal]
+
+// Restores the terminal before reporting an error with log.Fatal, which exits
+// right after writing, so the message isn't lost in the alternate buffer and
+// the shell isn't left in raw mode
+func fatal(v ...any) {
+	restoreOnExit()
+	log.Fatal(v...)
+}
+
+func fatalf(format string, v ...any) {
+	restoreOnExit()
+	log.Fatalf(format, v...)
+}
+
+func restoreOnExit() {
+	if terminal := fatalTerminal.Load(); terminal != nil {
+		terminal.Restore()
+	}
+}
+
+// Restores the terminal and prints the panic with its stack trace on the normal
+// screen. Has to be deferred directly by every goroutine that can panic.
+func (t *Terminal) RecoverPanic() {
+	if err := recover(); err != nil {
+		t.Restore()
+		exitOnPanic(err)
+	}
+}
+
+// Same as RecoverPanic, for the goroutines started where the terminal is not
+// at hand, like the input reader and the clock
+func recoverPanic() {
+	if err := recover(); err != nil {
+		restoreOnExit()
+		exitOnPanic(err)
+	}
+}
+
+func exitOnPanic(err any) {
+	fmt.Fprintf(os.Stderr, "panic: %v\n\n%s", err, debug.Stack())
+	os.Exit(2)
+}
+
+func (t *Terminal) installTeardown() {
+	if t.teardown {
+		return
+	}
+
+	t.teardown = true
+	fatalTerminal.Store(t)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, unix.SIGTERM, unix.SIGHUP, unix.SIGINT)
+
+	go func() {
+		sig := <-signals
+		t.Restore()
+		fmt.Fprintf(os.Stderr, "Terminated by signal: %s\n", sig)
+
+		code := 1
+		if number, ok := sig.(syscall.Signal); ok {
+			code = 128 + int(number)
+		}
+
+		os.Exit(code)
+	}()
+}
@@ -1,9 +1,9 @@
 package main
 
 import (
-	"log"
 	"os"
 	"strings"
+	"sync"
 
 	"golang.org/x/sys/unix"
 )
@@ -14,6 +14,7 @@ const (
 	TrueColor TerminalColor = iota
 	Color256
 	AnsiColor
+	NoColor
 )
 
 type Terminal struct {
@@ -21,21 +22,150 @@ type Terminal struct {
 	oldState       unix.Termios
 	currentState   unix.Termios
 	colorSupport   TerminalColor
+	forcedColor    bool
+	mouseMode      MouseMode
+	bracketedPaste bool
+	focusReporting bool
+	jobControl     bool
+
+	// Modes that were on when the process was suspended, turned back on when
+	// it resumes. Only recorded when the suspension went through Suspend.
+	suspendedModes terminalModes
+	suspended      bool
+
+	// Whether the terminal is set up and has to be restored
+	active   bool
+	teardown bool
+	mutex    sync.Mutex
+}
+
+type TerminalOption func(t *Terminal)
+
+// Reporting modes that can be turned on and off while the terminal is active
+type terminalModes struct {
+	mouseMode      MouseMode
+	bracketedPaste bool
+	focusReporting bool
+}
+
+// Reports mouse events using the given tracking mode
+func WithMouse(mode MouseMode) TerminalOption {
+	return func(t *Terminal) {
+		t.mouseMode = mode
+	}
+}
+
+// Delivers pasted text as a single OnPaste event instead of keystrokes
+func WithBracketedPaste() TerminalOption {
+	return func(t *Terminal) {
+		t.bracketedPaste = true
+	}
+}
+
+// Reports when the terminal, or the tmux pane, gains or loses focus
+func WithFocusReporting() TerminalOption {
+	return func(t *Terminal) {
+		t.focusReporting = true
+	}
 }
 
-func (t *Terminal) Init() {
-	t.colorSupport = t.GetBestColorSupport()
+// Lets Ctrl+Z suspend the application like any other job, restoring the
+// terminal while it is stopped
+func WithJobControl() TerminalOption {
+	return func(t *Terminal) {
+		t.jobControl = true
+	}
+}
+
+func (t *Terminal) Init(options ...TerminalOption) {
+	for _, option := range options {
+		option(t)
+	}
+
+	if !t.forcedColor {
+		t.colorSupport = t.GetBestColorSupport()
+	}
+
+	t.setup()
+}
+
+// Takes the terminal over, turning on the reporting modes currently set
+func (t *Terminal) setup() {
+	t.mutex.Lock()
+	t.active = true
+	t.mutex.Unlock()
+
+	t.installTeardown()
 	t.EnableRawMode()
 	t.HideCursor()
 	t.EnableAlternateBuffer()
 	t.ClearAlternateBuffer()
+	t.EnableMouse(t.mouseMode)
+
+	if t.bracketedPaste {
+		t.EnableBracketedPaste()
+	}
+
+	if t.focusReporting {
+		t.EnableFocusReporting()
+	}
+}
+
+func (t *Terminal) HasJobControl() bool {
+	return t.jobControl
+}
+
+// Restores the terminal and stops the process group, as the shell would do on
+// Ctrl+Z if the terminal was not in raw mode
+func (t *Terminal) Suspend() {
+	// Restore turns every mode off, so they are recorded first
+	t.suspendedModes = terminalModes{
+		mouseMode:      t.mouseMode,
+		bracketedPaste: t.bracketedPaste,
+		focusReporting: t.focusReporting,
+	}
+	t.suspended = true
+
+	t.Restore()
+	unix.Kill(0, unix.SIGTSTP)
+}
+
+// Sets the terminal up again after the process was continued, with the modes
+// that were on when it was suspended
+func (t *Terminal) Resume() {
+	// When stopped from outside the modes were never turned off, so the
+	// current ones are still right
+	if t.suspended {
+		t.mouseMode = t.suspendedModes.mouseMode
+		t.bracketedPaste = t.suspendedModes.bracketedPaste
+		t.focusReporting = t.suspendedModes.focusReporting
+		t.suspended = false
+	}
+
+	t.setup()
 }
 
 func (t *Terminal) GetColorSupport() TerminalColor {
 	return t.colorSupport
 }
 
+// Forces a color mode regardless of what the environment reports, mostly
+// useful for testing the output in each mode
+func (t *Terminal) ForceColorSupport(support TerminalColor) {
+	t.colorSupport = support
+	t.forcedColor = true
+}
+
 func (t *Terminal) GetBestColorSupport() TerminalColor {
+	if forced, ok := t.colorSupportOverride(); ok {
+		return forced
+	}
+
+	// https://no-color.org
+	if os.Getenv("NO_COLOR") != "" {
+		return NoColor
+	}
+
 	if t.SupportsTrueColor() {
 		return TrueColor
 	} else if t.Supports256Color() {
@@ -45,6 +175,23 @@ func (t *Terminal) GetBestColorSupport() TerminalColor {
 	}
 }
 
+// Reads the color mode from BKALPHA_COLOR, which accepts "truecolor",
+// "256", "ansi" and "none"
+func (t *Terminal) colorSupportOverride() (TerminalColor, bool) {
+	switch os.Getenv("BKALPHA_COLOR") {
+	case "truecolor", "24bit":
+		return TrueColor, true
+	case "256", "256color":
+		return Color256, true
+	case "ansi", "16":
+		return AnsiColor, true
+	case "none":
+		return NoColor, true
+	}
+
+	return AnsiColor, false
+}
+
 func (t *Terminal) SupportsTrueColor() bool {
 	colorTerm := os.Getenv("COLORTERM")
 	return colorTerm == "truecolor" || colorTerm == "24bit"
@@ -67,7 +214,7 @@ func (t *Terminal) GetTerminalSize() (int, int) {
 	ws, err := unix.IoctlGetWinsize(t.fileDescriptor, unix.TIOCGWINSZ)
 
 	if err != nil {
-		log.Fatalf("Could not access terminal size: %s", err.Error())
+		fatalf("Could not access terminal size: %s", err.Error())
 	}
 
 	return int(ws.Col), int(ws.Row)
@@ -77,12 +224,81 @@ func (t *Terminal) ApplyState(state *unix.Termios) {
 	unix.IoctlSetTermios(t.fileDescriptor, unix.TCSETS, state)
 }
 
+// Puts the terminal back into the state it was in before Init, it is safe to
+// call more than once and from any goroutine
 func (t *Terminal) Restore() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if !t.active {
+		return
+	}
+
+	t.active = false
 	t.ApplyState(&t.oldState)
+
+	// A frame may have been cut in the middle of a styled run
+	os.Stdout.Write([]byte(escResetStyle))
+	t.DisableMouse()
+	t.DisableBracketedPaste()
+	t.DisableFocusReporting()
 	t.DisableAlternateBuffer()
 	t.ShowCursor()
 }
 
+func (t *Terminal) EnableFocusReporting() {
+	os.Stdout.Write([]byte(escEnableFocus))
+	t.focusReporting = true
+}
+
+func (t *Terminal) DisableFocusReporting() {
+	if t.focusReporting {
+		os.Stdout.Write([]byte(escDisableFocus))
+		t.focusReporting = false
+	}
+}
+
+func (t *Terminal) EnableBracketedPaste() {
+	os.Stdout.Write([]byte(escEnablePaste))
+	t.bracketedPaste = true
+}
+
+func (t *Terminal) DisableBracketedPaste() {
+	if t.bracketedPaste {
+		os.Stdout.Write([]byte(escDisablePaste))
+		t.bracketedPaste = false
+	}
+}
+
+// Enables mouse tracking with SGR extended coordinates, which are not limited
+// to 223 columns like the legacy encoding
+func (t *Terminal) EnableMouse(mode MouseMode) {
+	t.DisableMouse()
+	t.mouseMode = mode
+
+	switch mode {
+	case MouseClick:
+		os.Stdout.Write([]byte(escEnableMouseClick + escEnableMouseSGR))
+	case MouseButtonEvent:
+		os.Stdout.Write([]byte(escEnableMouseButton + escEnableMouseSGR))
+	case MouseAnyMotion:
+		os.Stdout.Write([]byte(escEnableMouseAny + escEnableMouseSGR))
+	}
+}
+
+func (t *Terminal) DisableMouse() {
+	switch t.mouseMode {
+	case MouseClick:
+		os.Stdout.Write([]byte(escDisableMouseSGR + escDisableMouseClick))
+	case MouseButtonEvent:
+		os.Stdout.Write([]byte(escDisableMouseSGR + escDisableMouseButton))
+	case MouseAnyMotion:
+		os.Stdout.Write([]byte(escDisableMouseSGR + escDisableMouseAny))
+	}
+
+	t.mouseMode = MouseOff
+}
+
 func (t *Terminal) EnableRawMode() {
 	// Disables echo and canonical mode
 	t.currentState.Lflag &^= unix.ICANON | unix.ECHO | unix.ISIG
@@ -117,7 +333,7 @@ func NewTerminal() *Terminal {
 	termios, err := unix.IoctlGetTermios(fd, unix.TCGETS)
 
 	if err != nil {
-		log.Fatalf("Standard input is not a terminal: '%s'", err.Error())
+		fatalf("Standard input is not a terminal: '%s'", err.Error())
 	}
 
 	return &Terminal{
@@ -2,26 +2,103 @@ package main
 
 import (
 	"strings"
+	"unicode/utf8"
 )
 
-type WordInfo struct {
-	word   string
-	length int
-}
-
 type Text struct {
-	Text       string
+	Text string
+	// Styled pieces of text, shown instead of Text when set
+	Spans      []Span
 	Position   *Position
 	Dimensions *Dimensions
 	Padding    *Padding
 	Border     *Border
 	Props      *TextProps
+	Style      *Style
 }
 
 func (t *Text) Render() (*Matrix, int, int) {
-	data := newTextData(t.Text, t.Position, t.Dimensions, t.Padding, t.Border, t.Props)
-	x, y := data.getPosition()
-	textW, textH := t.calculateTextbox(data)
+	return renderComponent(t)
+}
+
+// Auto widths take the width of the text on a single line, unless that does
+// not fit the constraints, in which case the text wraps at the maximum width
+func (t *Text) Measure(constraints Constraints) (int, int) {
+	data := t.textData(constraints.parentSize())
+	data.constrain(constraints)
+	width, height := data.getDimensions()
+
+	if width <= 0 && constraints.MaxWidth > 0 {
+		natural := t.build(data, 0, height)
+
+		if natural.Width() > constraints.MaxWidth {
+			width = constraints.MaxWidth
+		}
+	}
+
+	matrix := t.build(data, width, height)
+	return constraints.Clamp(matrix.Width(), matrix.Height())
+}
+
+// The text is laid out again at the final size, so word wrap follows the width
+// given by the parent
+func (t *Text) Arrange(canvas *Matrix, rect Rect) {
+	data := t.textData(rect.Width, rect.Height)
+	canvas.Blit(rect, t.build(data, rect.Width, rect.Height))
+}
+
+// Number of lines left out when the text is laid out in a box of the given
+// size, so something like "+N more" can be shown next to it. Sizes of 0 are
+// taken from the dimensions.
+func (t *Text) HiddenLines(width int, height int) int {
+	data := t.textData(width, height)
+	maxLines, _, wordWrap := data.getProps()
+	paragraphs := data.getParagraphs()
+
+	if width <= 0 || height <= 0 {
+		dimW, dimH := data.getDimensions()
+		width, height = max(width, dimW), max(height, dimH)
+	}
+
+	textW, textH := t.calculateTextbox(width, height, data)
+
+	if textW <= 0 {
+		if textH <= 0 {
+			return 0
+		}
+		return max(len(paragraphs)-textH, 0)
+	}
+
+	if maxLines <= 0 {
+		maxLines = textH
+	}
+
+	if maxLines <= 0 {
+		return 0
+	}
+
+	lines := breakParagraphs(paragraphs, textW)
+	if wordWrap {
+		lines = wrapParagraphs(paragraphs, textW)
+	}
+
+	return max(len(lines)-maxLines, 0)
+}
+
+func (t *Text) locate(parentW int, parentH int, width int, height int) (int, int) {
+	return t.textData(parentW, parentH).getPosition(width, height)
+}
+
+func (t *Text) textData(parentW int, parentH int) *textData {
+	data := newTextData(t.Text, t.Spans, t.Position, t.Dimensions, t.Padding, t.Border, t.Props, t.Style)
+	data.setParent(parentW, parentH)
+	return data
+}
+
+// Builds the whole box, the width and height include padding and border and
+// an axis of 0 is sized after the text
+func (t *Text) build(data *textData, width int, height int) *Matrix {
+	textW, textH := t.calculateTextbox(width, height, data)
 	textMatrix := t.createTextMatrix(textW, textH, data)
 	spacedMatrix := t.calculateSpacing(textMatrix, data)
 
@@ -29,7 +106,7 @@ func (t *Text) Render() (*Matrix, int, int) {
 		t.placeBorder(spacedMatrix, data)
 	}
 
-	return spacedMatrix, x, y
+	return spacedMatrix
 }
 
 func (t *Text) calculateSpacing(matrix *Matrix, data *textData) *Matrix {
@@ -41,15 +118,12 @@ func (t *Text) calculateSpacing(matrix *Matrix, data *textData) *Matrix {
 	height := borderSize + pt + matrix.Height() + pb + borderSize
 
 	newMatrix := NewMatrix(width, height)
-	newMatrix.ForEach(func(colIndex int, rowIndex int, element rune, end bool) rune {
-		return rune('0')
-	})
+	newMatrix.Fill(NewCell(rune(' '), data.getStyle()))
 	newMatrix.PlaceMatrix(borderSize+pl+1, borderSize+pt+1, matrix)
 	return newMatrix
 }
 
-func (t *Text) calculateTextbox(data *textData) (int, int) {
-	width, height := data.getDimensions()
+func (t *Text) calculateTextbox(width int, height int, data *textData) (int, int) {
 	pt, pr, pb, pl := data.getPadding()
 	borderSize := BoolToInt(data.hasBorder())
 
@@ -75,36 +149,34 @@ func (t *Text) calculateTextbox(data *textData) (int, int) {
 }
 
 func (t *Text) createTextMatrix(textW int, textH int, data *textData) *Matrix {
-	maxLines, ellipsis, wordWrap := data.getProps()
+	maxLines, _, wordWrap := data.getProps()
+	overflow := data.getOverflow()
+	paragraphs := data.getParagraphs()
+	align, vertical := data.getAlign()
+	style := data.getStyle()
 
 	fixedH := textH > 0
 	fixedW := textW > 0
 
 	// If height is fixed but width is auto, the textbox will scale horizontally
-	// making the text single-line
+	// so each line of the text fits in a single row
 	if !fixedW && fixedH {
-		return t.placeSingleline(textH)
+		return alignVertically(t.placeSingleline(paragraphs, textH, align, style), textH, vertical, style)
 	}
 
 	// If both height and width are auto, the textbox will also scale horizontally
-	// making the text single-line
+	// and vertically take as many rows as the text has lines
 	if !fixedW && !fixedH {
-		return t.placeSingleline(1)
+		return t.placeSingleline(paragraphs, 0, align, style)
 	}
 
 	// If width is fixed and height is auto, the textbox will scale vertically
 	// allowing the text to have multiple lines
 	if fixedW && !fixedH {
-		newEllipsis := ellipsis
-
-		// Does not allow the ellipsis to show if the width is less than 4 characters
-		if textW < 4 {
-			newEllipsis = false
-		}
 		if wordWrap {
-			return t.placeMultilineWrap(textW, maxLines, newEllipsis)
+			return t.placeMultilineWrap(paragraphs, textW, maxLines, overflow, align, style)
 		} else {
-			return t.placeMultilineBW(textW, maxLines, newEllipsis)
+			return t.placeMultilineBW(paragraphs, textW, maxLines, overflow, align, style)
 		}
 	}
 
@@ -112,220 +184,371 @@ func (t *Text) createTextMatrix(textW int, textH int, data *textData) *Matrix {
 	// will be cropped if it does not fit inside the box
 	if fixedH && fixedW {
 		newMaxLines := maxLines
-		newEllipsis := ellipsis
 
 		// If max lines is not specified, it becomes equal to the height
 		if maxLines <= 0 {
 			newMaxLines = textH
 		}
 
-		// Does not allow the ellipsis to show if the width is less than 4 characters
-		if textW < 4 {
-			newEllipsis = false
-		}
-
 		var result *Matrix
 
 		if wordWrap {
-			result = t.placeMultilineWrap(textW, newMaxLines, newEllipsis)
+			result = t.placeMultilineWrap(paragraphs, textW, newMaxLines, overflow, align, style)
 		} else {
-			result = t.placeMultilineBW(textW, newMaxLines, newEllipsis)
+			result = t.placeMultilineBW(paragraphs, textW, newMaxLines, overflow, align, style)
 		}
 
 		// Enforce height on textbox
-		result.GrowV(textH - result.Height())
-
-		return result
+		return alignVertically(result, textH, vertical, style)
 	}
 
 	panic("Unkown error")
 }
 
-func (t *Text) placeMultilineWrap(width int, maxLines int, ellipsis bool) *Matrix {
-	rawWords := strings.Split(strings.Trim(t.Text, " "), " ")
+// Each line of the text is wrapped on its own. Lines with a word wider than
+// the width are broken as if break word was active.
+func (t *Text) placeMultilineWrap(
+	paragraphs [][]grapheme,
+	width int,
+	maxLines int,
+	overflow textOverflow,
+	align TextAlign,
+	style Style,
+) *Matrix {
+	lines := wrapParagraphs(paragraphs, width)
+	return t.placeLines(lines, width, maxLines, overflow, align, style)
+}
+
+// Used when break word is active, this algorithm breaks the words during
+// line wrapping, as opposed to just wrapping the entire word to the next
+// line
+func (t *Text) placeMultilineBW(
+	paragraphs [][]grapheme,
+	width int,
+	maxLines int,
+	overflow textOverflow,
+	align TextAlign,
+	style Style,
+) *Matrix {
+	lines := breakParagraphs(paragraphs, width)
+	return t.placeLines(lines, width, maxLines, overflow, align, style)
+}
 
-	// List to store words and their lengths
-	wordList := []WordInfo{}
+func wrapParagraphs(paragraphs [][]grapheme, width int) []textLine {
+	lines := []textLine{}
 
-	for _, w := range rawWords {
-		if len(w) > width {
-			return t.placeMultilineBW(width, maxLines, ellipsis)
-		}
-		wordList = append(wordList, WordInfo{word: w, length: len(w)})
+	for _, paragraph := range paragraphs {
+		lines = appendParagraph(lines, wrapWords(paragraph, width))
 	}
 
-	matrix := NewMatrix(width, 1)
+	return lines
+}
 
-	row := ""
-	line := 1
+func breakParagraphs(paragraphs [][]grapheme, width int) []textLine {
+	lines := []textLine{}
 
-	wordCount := len(wordList)
-	wordsRendered := 0
+	for _, paragraph := range paragraphs {
+		lines = appendParagraph(lines, breakGraphemes(paragraph, width))
+	}
 
-	// Iterate over the word list instead of a map
-	for _, wordInfo := range wordList {
-		rowSize := len(row)
+	return lines
+}
 
-		if rowSize+wordInfo.length <= width {
-			row += wordInfo.word
-			wordsRendered++
+func wrapWords(paragraph []grapheme, width int) [][]grapheme {
+	words := [][]grapheme{}
+	spaces := []grapheme{}
+	word := []grapheme{}
+
+	// Every space ends a word, even an empty one, so runs of spaces are kept
+	for _, g := range trimTrailingSpaces(paragraph) {
+		if g.isSpace() {
+			words = append(words, word)
+			spaces = append(spaces, g)
+			word = []grapheme{}
+			continue
+		}
 
-			// Recalculate row size
-			rowSize := len(row)
+		word = append(word, g)
+	}
 
-			if rowSize+1 <= width {
-				row += " "
-			}
-		} else {
-			diff := width - rowSize
+	words = append(words, word)
+	spaces = append(spaces, grapheme{text: " ", width: 1})
 
-			// Add spaces to fill the line
-			for i := 0; i < diff; i++ {
-				row += " "
-			}
+	for _, word := range words {
+		if graphemesWidth(word) > width {
+			return breakGraphemes(paragraph, width)
+		}
+	}
 
-			// Place the row into the matrix
-			for i, r := range row {
-				matrix.Place(i+1, line, r)
-			}
+	lines := [][]grapheme{}
+	line := []grapheme{}
+	used := 0
 
-			// Reset row for the next line
-			row = ""
-			line++
+	for i, word := range words {
+		wordWidth := graphemesWidth(word)
 
-			if maxLines > 0 && line > maxLines {
-				break
-			}
+		if used > 0 && used+wordWidth > width {
+			lines = append(lines, line)
+			line = []grapheme{}
+			used = 0
+		}
 
-			matrix.GrowV(1)
+		line = append(line, word...)
+		used += wordWidth
 
-			// Add the current word in the row
-			row += wordInfo.word
-			wordsRendered++
+		if used+1 <= width {
+			line = append(line, spaces[i])
+			used++
+		}
+	}
 
-			rowSize = len(row)
+	return append(lines, line)
+}
 
-			if rowSize+1 <= width {
-				row += " "
-			}
+// Breaks the paragraph wherever the width is reached. Wide characters that do
+// not fit at the end of a line are moved to the next one, so characters are
+// never split, and spaces at the start of the wrapped lines are dropped.
+func breakGraphemes(paragraph []grapheme, width int) [][]grapheme {
+	lines := [][]grapheme{}
+	line := []grapheme{}
+	used := 0
+
+	for _, g := range paragraph {
+		// A character wider than the whole line can never be shown
+		if g.width > width {
+			g = grapheme{text: string(utf8.RuneError), width: 1, style: g.style}
+		}
+
+		if used+g.width > width {
+			lines = append(lines, line)
+			line = []grapheme{}
+			used = 0
 		}
-	}
 
-	// If there's any leftover row, add it to the matrix
-	if row != "" {
-		for i, r := range row {
-			matrix.Place(i+1, line, r)
+		if used == 0 && len(lines) > 0 && g.isSpace() {
+			continue
 		}
+
+		line = append(line, g)
+		used += g.width
 	}
 
-	// Add ellipis if specified
-	if ellipsis && (wordCount > wordsRendered) {
-		raw := matrix.GetRow(maxLines)
-		lastLine := ""
+	return append(lines, line)
+}
 
-		for _, r := range raw {
-			lastLine += string(r)
-		}
+// Line of wrapped text, the last one of a paragraph is never justified
+type textLine struct {
+	graphemes []grapheme
+	last      bool
+}
 
-		lastLine = strings.TrimRight(lastLine, " ")
-		ellipsis := "..."
+func appendParagraph(lines []textLine, wrapped [][]grapheme) []textLine {
+	for i, line := range wrapped {
+		lines = append(lines, textLine{graphemes: line, last: i == len(wrapped)-1})
+	}
 
-		for len(lastLine)+3 > width {
-			lastLine = lastLine[:len(lastLine)-1]
-		}
+	return lines
+}
 
-		lastLine += ellipsis
-		finalRunes := []rune{}
+// Places the lines into a matrix of the given width, cutting them at the
+// maximum number of lines as the overflow says
+func (t *Text) placeLines(
+	lines []textLine,
+	width int,
+	maxLines int,
+	overflow textOverflow,
+	align TextAlign,
+	style Style,
+) *Matrix {
+	lines = overflow.truncate(lines, width, maxLines)
+
+	matrix := NewMatrix(width, max(len(lines), 1))
+
+	for i, line := range lines {
+		placeAligned(matrix, i+1, line, align, style)
+	}
 
-		for _, r := range lastLine {
-			finalRunes = append(finalRunes, r)
-		}
+	return matrix
+}
+
+// Places every line of the text in its own row, taking as many rows as there
+// are lines, or at most the given number of rows when it is not 0
+func (t *Text) placeSingleline(paragraphs [][]grapheme, rows int, align TextAlign, style Style) *Matrix {
+	if rows <= 0 || rows > len(paragraphs) {
+		rows = len(paragraphs)
+	}
 
-		matrix.PlaceRow(1, maxLines, finalRunes)
+	lines := []textLine{}
+	width := 1
+
+	for _, line := range paragraphs[:rows] {
+		lines = append(lines, textLine{graphemes: line, last: true})
+		width = max(width, graphemesWidth(line))
+	}
+
+	matrix := NewMatrix(width, rows)
+
+	for i, line := range lines {
+		placeAligned(matrix, i+1, line, align, style)
 	}
 
 	return matrix
 }
 
-// Used when break word is active, this algorithm breaks the words during
-// line wrapping, as opposed to just wrapping the entire word to the next
-// line
-func (t *Text) placeMultilineBW(width int, maxLines int, ellipsis bool) *Matrix {
-	size := len(t.Text)
-	matrix := NewMatrix(width, 1)
+// Places the line in the row, aligned inside the width of the matrix, and
+// fills the rest of the row so the style covers the whole box
+func placeAligned(matrix *Matrix, row int, line textLine, align TextAlign, style Style) {
+	width := matrix.Width()
+	graphemes := line.graphemes
+
+	// The space left after the last word by wrapping is not part of the text
+	if align != TextAlignLeft {
+		graphemes = trimTrailingSpaces(graphemes)
+	}
+
+	if align == TextAlignJustify && !line.last {
+		graphemes = justify(graphemes, width)
+	}
 
-	line := 1
-	index := 0
 	offset := 0
+	free := max(width-graphemesWidth(graphemes), 0)
 
-	for i := 1; i <= size; i++ {
-		r := rune(t.Text[index])
-		x := (i - offset) % width
+	switch align {
+	case TextAlignCenter:
+		offset = free / 2
+	case TextAlignRight:
+		offset = free
+	}
 
-		if x == 1 && r == rune(' ') {
-			offset++
-			index++
-			continue
-		}
+	for x := 1; x <= width; x++ {
+		matrix.Place(x, row, NewCell(' ', style))
+	}
+
+	matrix.placeGraphemes(offset+1, row, graphemes, style)
+}
 
-		if x == 0 {
-			x = width
+// Widens the spaces between the words so the line takes the whole width.
+// Spaces indenting the line are kept as they are.
+func justify(line []grapheme, width int) []grapheme {
+	free := width - graphemesWidth(line)
+	gaps := []int{}
+	indent := true
+
+	for i, g := range line {
+		if !g.isSpace() {
+			indent = false
+		} else if !indent && !line[i-1].isSpace() {
+			gaps = append(gaps, i)
 		}
+	}
 
-		matrix.Place(x, line, r)
+	if free <= 0 || len(gaps) == 0 {
+		return line
+	}
 
-		if (i-offset)%width == 0 {
-			line++
+	weights := make([]float64, len(gaps))
+	for i := range weights {
+		weights[i] = 1
+	}
+
+	extra := spread(free, weights)
+	result := []grapheme{}
+	gap := 0
 
-			if maxLines > 0 && line > maxLines {
-				// Add ellipsis if specified
-				if ellipsis && i < size {
-					raw := matrix.GetRow(maxLines)
-					lastLine := ""
+	for i, g := range line {
+		if gap < len(gaps) && gaps[gap] == i {
+			for range extra[gap] {
+				result = append(result, g)
+			}
+			gap++
+		}
 
-					for _, r := range raw {
-						lastLine += string(r)
-					}
+		result = append(result, g)
+	}
 
-					lastLine = strings.TrimRight(lastLine, " ")
-					ellipsis := "..."
+	return result
+}
 
-					for len(lastLine)+3 > width {
-						lastLine = lastLine[:len(lastLine)-1]
-					}
+// Makes the matrix as tall as the height, placing its rows at the top, middle
+// or bottom. Matrices that are already taller are kept as they are.
+func alignVertically(matrix *Matrix, height int, vertical VerticalAlign, style Style) *Matrix {
+	if matrix.Height() >= height {
+		return matrix
+	}
 
-					lastLine += ellipsis
-					finalRunes := []rune{}
+	offset := 0
+	free := height - matrix.Height()
 
-					for _, r := range lastLine {
-						finalRunes = append(finalRunes, r)
-					}
+	switch vertical {
+	case VerticalMiddle:
+		offset = free / 2
+	case VerticalBottom:
+		offset = free
+	}
 
-					matrix.PlaceRow(1, maxLines, finalRunes)
-				}
-				break
-			}
+	result := NewMatrix(matrix.Width(), height)
+	result.Fill(NewCell(' ', style))
+	result.PlaceMatrix(1, offset+1, matrix)
 
-			if i < size {
-				matrix.GrowV(1)
-			}
-		}
+	return result
+}
 
-		index++
+func trimTrailingSpaces(line []grapheme) []grapheme {
+	for len(line) > 0 && line[len(line)-1].isSpace() {
+		line = line[:len(line)-1]
 	}
 
-	return matrix
+	return line
 }
 
-func (t *Text) placeSingleline(height int) *Matrix {
-	size := len(t.Text)
-	matrix := NewMatrix(size, height)
+// Replaces every tab with spaces up to the next tab stop, counted from the
+// start of the line
+func expandTabs(line []grapheme, tabSize int) []grapheme {
+	result := []grapheme{}
+	column := 0
 
-	for i, r := range t.Text {
-		matrix.Place(i+1, 1, r)
+	for _, g := range line {
+		if g.text != "\t" {
+			result = append(result, g)
+			column += g.width
+			continue
+		}
+
+		for range tabSize - column%tabSize {
+			result = append(result, grapheme{text: " ", width: 1, style: g.style})
+			column++
+		}
 	}
 
-	return matrix
+	return result
+}
+
+// Removes the control characters other than tabs, or replaces them with
+// visible placeholders, since writing them would mess with the terminal
+func sanitizeControl(line string, control ControlChars) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t':
+			return r
+		case r < 0x20:
+			if control == ControlPictures {
+				return 0x2400 + r
+			}
+			return -1
+		case r == 0x7f:
+			if control == ControlPictures {
+				return 0x2421
+			}
+			return -1
+		case r >= 0x80 && r < 0xa0:
+			if control == ControlPictures {
+				return utf8.RuneError
+			}
+			return -1
+		}
+
+		return r
+	}, line)
 }
 
 func (t *Text) placeBorder(matrix *Matrix, data *textData) {
@@ -334,67 +557,90 @@ func (t *Text) placeBorder(matrix *Matrix, data *textData) {
 	}
 
 	btl, bt, btr, br, bbl, bb, bbr, bl := data.getBorderChars()
-	matrix.Border(1, bt, bl, bb, br, btl, btr, bbl, bbr)
+	matrix.Border(1, data.getStyle(), bt, bl, bb, br, btl, btr, bbl, bbr)
 }
 
 type textData struct {
-	text       string
-	position   *Position
-	dimensions *Dimensions
-	padding    *Padding
-	border     *Border
-	props      *TextProps
+	*boxData
+	text  string
+	spans []Span
+	props *TextProps
 }
 
-func (d *textData) getText() string {
-	return strings.Trim(d.text, " ")
-}
+// Lines of the text as separated by its line breaks, with tabs expanded and
+// control characters handled as the props say. Spans are used instead of the
+// text when there are any, each grapheme keeping the style of its span.
+func (d *textData) getParagraphs() [][]grapheme {
+	tabSize, control := defaultTabSize, ControlStrip
 
-func (d *textData) getPosition() (int, int) {
-	if d.position != nil {
-		return d.position.Eval()
+	if d.props != nil {
+		control = d.props.control
+
+		if d.props.tabSize > 0 {
+			tabSize = d.props.tabSize
+		}
 	}
-	return 1, 1
-}
 
-func (d *textData) getDimensions() (int, int) {
-	if d.dimensions != nil {
-		return d.dimensions.Eval()
+	spans := d.spans
+	if len(spans) == 0 {
+		spans = []Span{{Text: d.text}}
 	}
-	return 1, 1
-}
 
-func (d *textData) getPadding() (int, int, int, int) {
-	if d.padding != nil {
-		return d.padding.Eval()
+	paragraphs := [][]grapheme{{}}
+
+	for _, span := range spans {
+		text := strings.ReplaceAll(span.Text, "\r\n", "\n")
+		text = strings.ReplaceAll(text, "\r", "\n")
+
+		for i, part := range strings.Split(text, "\n") {
+			if i > 0 {
+				paragraphs = append(paragraphs, []grapheme{})
+			}
+
+			last := len(paragraphs) - 1
+			graphemes := splitGraphemes(sanitizeControl(part, control))
+
+			for _, g := range graphemes {
+				g.style = span.Style
+				paragraphs[last] = append(paragraphs[last], g)
+			}
+		}
 	}
-	return 0, 0, 0, 0
-}
 
-func (d *textData) getBorders() (bool, bool, bool, bool) {
-	if d.border != nil {
-		return d.border.Eval()
+	for i, paragraph := range paragraphs {
+		paragraphs[i] = expandTabs(paragraph, tabSize)
 	}
-	return false, false, false, false
+
+	return paragraphs
 }
 
-func (d *textData) getBorderSizes() (int, int, int, int) {
-	if d.border != nil {
-		return d.border.EvalSizes()
+func (d *textData) getAlign() (TextAlign, VerticalAlign) {
+	if d.props != nil {
+		return d.props.align, d.props.vertical
 	}
-	return 0, 0, 0, 0
+	return TextAlignLeft, VerticalTop
 }
 
-func (d *textData) getBorderChars() (rune, rune, rune, rune, rune, rune, rune, rune) {
-	if d.border != nil {
-		return d.border.EvalBorderRunes()
+func (d *textData) getOverflow() textOverflow {
+	overflow := textOverflow{mode: OverflowClip, marker: defaultOverflowMarker}
+
+	if d.props == nil {
+		return overflow
+	}
+
+	if d.props.ellipsis {
+		overflow.mode = OverflowEnd
+	}
+
+	if d.props.overflow != OverflowDefault {
+		overflow.mode = d.props.overflow
+	}
+
+	if d.props.marker != "" {
+		overflow.marker = d.props.marker
 	}
-	return rune(' '), rune(' '), rune(' '), rune(' '), rune(' '), rune(' '), rune(' '), rune(' ')
-}
 
-func (d *textData) hasBorder() bool {
-	bt, br, bb, bl := d.getBorderSizes()
-	return bt+br+bb+bl > 0
+	return overflow
 }
 
 func (d *textData) getProps() (int, bool, bool) {
@@ -406,18 +652,18 @@ func (d *textData) getProps() (int, bool, bool) {
 
 func newTextData(
 	text string,
+	spans []Span,
 	position *Position,
 	dimensions *Dimensions,
 	padding *Padding,
 	border *Border,
 	props *TextProps,
+	style *Style,
 ) *textData {
 	return &textData{
-		text:       text,
-		position:   position,
-		dimensions: dimensions,
-		padding:    padding,
-		border:     border,
-		props:      props,
+		boxData: newBoxData(position, dimensions, padding, border, style),
+		text:    text,
+		spans:   spans,
+		props:   props,
 	}
 }
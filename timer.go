@@ -0,0 +1,151 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Timer started through the context. It belongs to the screen that started it
+// and is stopped when that screen is destroyed, its events are only delivered
+// to that screen.
+type Timer struct {
+	context  *Context
+	clock    Clock
+	owner    *screenEntry
+	interval time.Duration
+	repeat   bool
+	frame    bool
+
+	handle  ClockTimer
+	last    time.Time
+	count   int
+	stopped bool
+	// An event of the timer is waiting in the loop, ticks until it is handled
+	// are merged into it
+	pending bool
+	mutex   sync.Mutex
+}
+
+// Stops the timer, no more events are posted after it returns
+func (t *Timer) Stop() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.stopped = true
+
+	if t.handle != nil {
+		t.handle.Stop()
+	}
+}
+
+func (t *Timer) Stopped() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.stopped
+}
+
+func (t *Timer) start() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.last = t.clock.Now()
+	t.handle = t.clock.AfterFunc(t.interval, t.fire)
+}
+
+func (t *Timer) fire() {
+	t.mutex.Lock()
+
+	if t.stopped {
+		t.mutex.Unlock()
+		return
+	}
+
+	now := t.clock.Now()
+	t.count++
+
+	if t.repeat {
+		t.handle = t.clock.AfterFunc(t.interval, t.fire)
+	} else {
+		t.stopped = true
+	}
+
+	if t.pending {
+		t.mutex.Unlock()
+		return
+	}
+
+	delta := now.Sub(t.last)
+	t.last = now
+	t.pending = true
+
+	var event Event
+
+	if t.frame {
+		event = &OnTick{Timer: t, Time: now, Delta: delta, Frame: t.count}
+	} else {
+		event = &OnTimer{Timer: t, Time: now, Count: t.count}
+	}
+
+	t.mutex.Unlock()
+
+	t.context.post(t.owner, event, t.handled)
+}
+
+func (t *Timer) handled() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.pending = false
+}
+
+// Posts a single OnTimer event once the duration has passed
+func (c *Context) After(d time.Duration) *Timer {
+	return c.startTimer(d, false, false)
+}
+
+// Posts an OnTimer event every time the interval passes, until stopped
+func (c *Context) Every(interval time.Duration) *Timer {
+	return c.startTimer(interval, true, false)
+}
+
+// Posts an OnTick event every frame, following the frame rate of the
+// renderer, until stopped. Meant for animations, the delta of each tick is the
+// time since the previous one.
+func (c *Context) FrameTick() *Timer {
+	c.mutex.Lock()
+	interval := c.frameInterval
+	c.mutex.Unlock()
+
+	return c.startTimer(interval, true, true)
+}
+
+func (c *Context) startTimer(interval time.Duration, repeat bool, frame bool) *Timer {
+	// A repeating timer without interval would never let the loop rest
+	if repeat && interval <= 0 {
+		interval = time.Second / defaultFrameRate
+	}
+
+	c.mutex.Lock()
+	owner := c.owner
+	clock := c.clock
+	c.mutex.Unlock()
+
+	timer := &Timer{
+		context:  c,
+		clock:    clock,
+		owner:    owner,
+		interval: interval,
+		repeat:   repeat,
+		frame:    frame,
+	}
+
+	if owner != nil && !owner.track(timer) {
+		// The screen is already destroyed
+		timer.stopped = true
+		return timer
+	}
+
+	timer.start()
+	return timer
+}
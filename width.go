@@ -0,0 +1,93 @@
+package main
+
+import (
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+)
+
+// User perceived character, a base rune with any combining marks or joined
+// emoji, along with the number of columns it takes in the terminal. The style
+// comes from the span the grapheme belongs to, nil uses the one of the
+// component.
+type grapheme struct {
+	text  string
+	width int
+	style *Style
+}
+
+func (g grapheme) isSpace() bool {
+	return g.text == " "
+}
+
+// Splits the text into graphemes. Zero width graphemes, which only appear
+// when the text starts with a combining mark, are kept as one column so they
+// are not lost.
+func splitGraphemes(text string) []grapheme {
+	graphemes := []grapheme{}
+	state := -1
+
+	for len(text) > 0 {
+		var cluster string
+		var width int
+
+		cluster, text, width, state = uniseg.FirstGraphemeClusterInString(text, state)
+		graphemes = append(graphemes, grapheme{text: cluster, width: max(width, 1)})
+	}
+
+	return graphemes
+}
+
+func graphemesWidth(graphemes []grapheme) int {
+	width := 0
+
+	for _, g := range graphemes {
+		width += g.width
+	}
+
+	return width
+}
+
+// Number of columns the text takes in the terminal
+func StringWidth(text string) int {
+	return graphemesWidth(splitGraphemes(text))
+}
+
+func isWideCell(cell Cell) bool {
+	if cell.Continuation {
+		return false
+	}
+
+	// Everything below the Hangul Jamo block is narrow
+	if cell.Extra == "" && cell.Rune < 0x1100 {
+		return false
+	}
+
+	return uniseg.StringWidth(cell.String()) == 2
+}
+
+// Text printed for the cell at the index of the row. Wide characters are
+// printed by their first cell only, and halves left alone when the other one
+// was overwritten are printed as spaces, so the columns of the row never
+// shift.
+func cellText(row []Cell, index int) string {
+	cell := row[index]
+
+	if cell.Continuation {
+		if index > 0 && isWideCell(row[index-1]) {
+			return ""
+		}
+		return " "
+	}
+
+	if isWideCell(cell) && (index+1 >= len(row) || !row[index+1].Continuation) {
+		return " "
+	}
+
+	return cell.String()
+}
+
+func firstRune(text string) (rune, string) {
+	r, size := utf8.DecodeRuneInString(text)
+	return r, text[size:]
+}